@@ -0,0 +1,183 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/uhhc/sdk-common-go/log"
+)
+
+// Loader loads a typed config from env vars, config files and flags
+// A key is looked up in the order of the changed flags, env vars, files, the default tag and the flag defaults.
+//
+// The fields are mapped by the struct tags:
+//
+// 		config:"name"    the key of the field, it is prefixed by the keys of the parent sections
+// 		default:"value"  the value if the key is not set
+// 		required:"true"  the key must be set to a non-zero value
+// 		unit:"ms"        the unit of the numbers for time.Duration, which is one of ns, us, ms, s, m and h, s by default
+//
+// The key "db.host" is read from the env var DB_HOST, from the files like "db: {host: ...}",
+// and from the flag named "db.host" or "db-host".
+type Loader struct {
+	files     []string
+	flags     *pflag.FlagSet
+	envPrefix string
+	logger    *log.Logger
+	global    bool
+}
+
+// Option changes the loader
+type Option func(*Loader)
+
+// WithFile to read a YAML, JSON or TOML file, the type is got from the extension
+// The later files override the earlier ones.
+func WithFile(path string) Option {
+	return func(l *Loader) {
+		l.files = append(l.files, path)
+	}
+}
+
+// WithFlags to read the flags, which should be parsed before the config is loaded
+// The flags of the standard library can be added by pflag.FlagSet.AddGoFlagSet.
+func WithFlags(flags *pflag.FlagSet) Option {
+	return func(l *Loader) {
+		l.flags = flags
+	}
+}
+
+// WithEnvPrefix to read the env vars with the prefix, such as APP_DB_HOST for the prefix "APP"
+func WithEnvPrefix(prefix string) Option {
+	return func(l *Loader) {
+		l.envPrefix = prefix
+	}
+}
+
+// WithLogger to log the reloads of the watcher, the errors are written to stderr if it is not set
+func WithLogger(logger *log.Logger) Option {
+	return func(l *Loader) {
+		l.logger = logger
+	}
+}
+
+// NewLoader to get a loader of the sources
+func NewLoader(opts ...Option) *Loader {
+	l := &Loader{}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Load to load the config into out, which is a pointer to a struct
+// It is a shortcut of NewLoader(opts...).Load(out).
+// Example:
+//
+// 		type AppConfig struct {
+// 			Log     log.Option      `config:"log"`
+// 			DB      db.Config       `config:"db"`
+// 			MongoDB *mongodb.Config `config:"mongodb"`
+// 			Port    int             `config:"port" default:"8080"`
+// 		}
+//
+// 		var cfg AppConfig
+// 		if err := config.Load(&cfg, config.WithFile("config.yaml")); err != nil {
+// 			panic(err)
+// 		}
+// 		logger := log.NewLogger(&cfg.Log)
+// 		dbClient, err := db.NewDB(*logger, &cfg.DB)
+//
+func Load(out interface{}, opts ...Option) error {
+	return NewLoader(opts...).Load(out)
+}
+
+// Load to load the config into out, which is a pointer to a struct
+// The struct fields are sections if they are tagged structs, a section of pointer is optional,
+// it is kept nil unless any of its keys is set.
+// The error is a *ValidationError if some keys are missing or invalid, or the Validate method of
+// the struct or a section returns an error.
+func (l *Loader) Load(out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: out should be a pointer to a struct, not %T", out)
+	}
+	_, err := l.load(rv.Elem())
+	return err
+}
+
+// load to decode the sources into rv, and get the decoded values by keys
+func (l *Loader) load(rv reflect.Value) (map[string]interface{}, error) {
+	v, err := l.newViper()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{v: v, flags: l.flags, envPrefix: l.envPrefix, values: map[string]interface{}{}}
+	d.decodeStruct(rv, "")
+	if len(d.missing) > 0 || len(d.invalid) > 0 {
+		return nil, &ValidationError{Missing: d.missing, Invalid: d.invalid}
+	}
+	if l.global {
+		applyGlobal(v)
+	}
+	return d.values, nil
+}
+
+func (l *Loader) newViper() (*viper.Viper, error) {
+	v := viper.New()
+	if l.envPrefix != "" {
+		v.SetEnvPrefix(l.envPrefix)
+	}
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	for i, file := range l.files {
+		v.SetConfigFile(file)
+		var err error
+		if i == 0 {
+			err = v.ReadInConfig()
+		} else {
+			err = v.MergeInConfig()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("config: read %s error: %w", file, err)
+		}
+	}
+	return v, nil
+}
+
+// Validator can be implemented by the config struct and its sections to be validated after they are loaded
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError is returned when some keys are missing or invalid
+type ValidationError struct {
+	// Missing are the required keys which are not set, with their env vars
+	Missing []string
+	// Invalid are the keys whose values can not be converted, with the reasons
+	Invalid []string
+}
+
+// Error implements error interface
+func (e *ValidationError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing keys: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Invalid) > 0 {
+		parts = append(parts, "invalid keys: "+strings.Join(e.Invalid, ", "))
+	}
+	return "config: " + strings.Join(parts, "; ")
+}
+
+// IsValidationError to check whether the error is a validation error
+func IsValidationError(err error) bool {
+	var ve *ValidationError
+	return errors.As(err, &ve)
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// decoder sets the tagged fields and collects the missing and invalid keys
+type decoder struct {
+	v         *viper.Viper
+	flags     *pflag.FlagSet
+	envPrefix string
+	missing   []string
+	invalid   []string
+	// values are the decoded values by keys, which are compared to find the changed keys on reload
+	values map[string]interface{}
+}
+
+// decodeStruct to decode the tagged fields of rv, and report whether any key is set
+func (d *decoder) decodeStruct(rv reflect.Value, prefix string) bool {
+	set := false
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name := field.Tag.Get("config")
+		if name == "" || name == "-" || field.PkgPath != "" {
+			continue
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		fv := rv.Field(i)
+		if isSection(field.Type) {
+			set = d.decodeSection(fv, key) || set
+		} else {
+			set = d.decodeField(fv, field, key) || set
+		}
+	}
+
+	if v, ok := rv.Addr().Interface().(Validator); ok {
+		if err := v.Validate(); err != nil {
+			msg := err.Error()
+			// The errors of the SDK packages are already prefixed, such as "log: ..."
+			if prefix != "" && !strings.HasPrefix(msg, prefix+": ") {
+				msg = prefix + ": " + msg
+			}
+			d.invalid = append(d.invalid, msg)
+		}
+	}
+	return set
+}
+
+func isSection(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// decodeSection to decode a nested struct, the nil pointer is set only if any key of the section is set,
+// so that the required keys of an absent optional section are not reported
+func (d *decoder) decodeSection(fv reflect.Value, key string) bool {
+	if fv.Kind() != reflect.Ptr {
+		return d.decodeStruct(fv, key)
+	}
+	if !fv.IsNil() {
+		return d.decodeStruct(fv.Elem(), key)
+	}
+
+	sub := &decoder{v: d.v, flags: d.flags, envPrefix: d.envPrefix, values: map[string]interface{}{}}
+	section := reflect.New(fv.Type().Elem())
+	if !sub.decodeStruct(section.Elem(), key) {
+		return false
+	}
+	fv.Set(section)
+	d.missing = append(d.missing, sub.missing...)
+	d.invalid = append(d.invalid, sub.invalid...)
+	for k, v := range sub.values {
+		d.values[k] = v
+	}
+	return true
+}
+
+func (d *decoder) decodeField(fv reflect.Value, field reflect.StructField, key string) bool {
+	raw, set := d.lookup(key)
+	if !set && isZero(fv) {
+		if def, ok := field.Tag.Lookup("default"); ok {
+			raw = def
+		} else if f := d.flag(key); f != nil {
+			raw = strings.Trim(f.DefValue, "[]")
+		}
+	}
+	if raw != nil {
+		if err := setValue(fv, raw, field.Tag.Get("unit")); err != nil {
+			d.invalid = append(d.invalid, fmt.Sprintf("%s: %v", d.describe(key), err))
+			return set
+		}
+	}
+	if field.Tag.Get("required") == "true" && isZero(fv) {
+		d.missing = append(d.missing, d.describe(key))
+	}
+	d.values[key] = fv.Interface()
+	return set
+}
+
+// lookup to get the value of the changed flag, env var or file
+func (d *decoder) lookup(key string) (interface{}, bool) {
+	if f := d.flag(key); f != nil && f.Changed {
+		if err := d.v.BindPFlag(key, f); err == nil {
+			return d.v.Get(key), true
+		}
+	}
+	if d.v.IsSet(key) {
+		return d.v.Get(key), true
+	}
+	return nil, false
+}
+
+// flag to get the flag named as the key, or the key with dashes
+func (d *decoder) flag(key string) *pflag.Flag {
+	if d.flags == nil {
+		return nil
+	}
+	if f := d.flags.Lookup(key); f != nil {
+		return f
+	}
+	return d.flags.Lookup(strings.NewReplacer(".", "-", "_", "-").Replace(key))
+}
+
+// describe to get the key with its env var for the messages
+func (d *decoder) describe(key string) string {
+	env := strings.ToUpper(strings.Replace(key, ".", "_", -1))
+	if d.envPrefix != "" {
+		env = strings.ToUpper(d.envPrefix) + "_" + env
+	}
+	return fmt.Sprintf("%s (%s)", key, env)
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func setValue(fv reflect.Value, raw interface{}, unit string) error {
+	if fv.Kind() == reflect.Ptr {
+		elem := reflect.New(fv.Type().Elem())
+		if err := setValue(elem.Elem(), raw, unit); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+	if fv.Type() == durationType {
+		duration, err := toDuration(raw, unit)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(duration))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		s, err := cast.ToStringE(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := cast.ToBoolE(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := cast.ToInt64E(raw)
+		if err != nil {
+			return err
+		}
+		if fv.OverflowInt(n) {
+			return fmt.Errorf("%d overflows %s", n, fv.Type())
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := cast.ToUint64E(raw)
+		if err != nil {
+			return err
+		}
+		if fv.OverflowUint(n) {
+			return fmt.Errorf("%d overflows %s", n, fv.Type())
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := cast.ToFloat64E(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		list, err := toStringList(raw)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(list).Convert(fv.Type()))
+	case reflect.Map:
+		if fv.Type().Key().Kind() != reflect.String || fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		m, err := toStringMap(raw)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(m).Convert(fv.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// toDuration to convert a number in the unit, or a string like "1m30s"
+func toDuration(raw interface{}, unit string) (time.Duration, error) {
+	if unit == "" {
+		unit = "s"
+	}
+	u, ok := durationUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid unit %q", unit)
+	}
+	if s, ok := raw.(string); ok {
+		s = strings.TrimSpace(s)
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return time.Duration(f * float64(u)), nil
+		}
+		return time.ParseDuration(s)
+	}
+	f, err := cast.ToFloat64E(raw)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(f * float64(u)), nil
+}
+
+// toStringList to convert a list of a file, or a string like "a,b" of an env var
+func toStringList(raw interface{}) ([]string, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return cast.ToStringSliceE(raw)
+	}
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// toStringMap to convert a map of a file, or a string like "k1=v1,k2=v2" of an env var
+func toStringMap(raw interface{}) (map[string]string, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return cast.ToStringMapStringE(raw)
+	}
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid pair %q, it should be key=value", pair)
+		}
+		m[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return m, nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	globalEnvOnce sync.Once
+
+	// globalMu serializes the loaders applying their files to the global viper
+	globalMu   sync.Mutex
+	globalKeys = map[string]bool{}
+)
+
+// GlobalEnv to make the global viper read the env vars
+// The SDK packages which read their default config from the global viper, such as httpclient and mongodb,
+// call it instead of viper.AutomaticEnv, so that the global viper is changed only once.
+func GlobalEnv() {
+	globalEnvOnce.Do(viper.AutomaticEnv)
+}
+
+// WithGlobal to apply the files to the global viper every time the config is loaded, so that the SDK packages
+// reading the global viper, such as httpclient.NewClient() and mongodb.NewMongoClient(), get the same config
+// as the loader
+// The keys are joined by "_", such as HTTP_CLIENT_TIMEOUT for "http_client.timeout", and the env vars still
+// take precedence. The env prefix of the loader is not applied to the global viper.
+func WithGlobal() Option {
+	return func(l *Loader) {
+		l.global = true
+	}
+}
+
+// applyGlobal to set the keys of the files as the defaults of the global viper, the keys which are removed
+// from the files since the last load are cleared
+func applyGlobal(v *viper.Viper) {
+	GlobalEnv()
+
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	keys := map[string]bool{}
+	for _, key := range v.AllKeys() {
+		name := globalKey(key)
+		keys[name] = true
+		viper.SetDefault(name, v.Get(key))
+	}
+	for name := range globalKeys {
+		if !keys[name] {
+			viper.SetDefault(name, nil)
+		}
+	}
+	globalKeys = keys
+}
+
+func globalKey(key string) string {
+	return strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestWithGlobal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.yaml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = os.Setenv("HTTP_CLIENT_USER_AGENT", "from-env")
+	defer os.Unsetenv("HTTP_CLIENT_USER_AGENT")
+
+	type appConfig struct {
+		Timeout int `config:"http_client.timeout"`
+	}
+	var cfg appConfig
+	loader := NewLoader(WithFile(file), WithGlobal())
+	write("http_client:\n  timeout: 3\n  user_agent: from-file\n  base_url: http://example.com\n")
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := viper.GetInt("HTTP_CLIENT_TIMEOUT"); got != 3 {
+		t.Errorf("got HTTP_CLIENT_TIMEOUT %d, want 3 from the file", got)
+	}
+	if got := viper.GetString("HTTP_CLIENT_USER_AGENT"); got != "from-env" {
+		t.Errorf("got HTTP_CLIENT_USER_AGENT %q, want the env var", got)
+	}
+
+	// The removed keys are cleared
+	write("http_client:\n  timeout: 5\n")
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := viper.GetInt("HTTP_CLIENT_TIMEOUT"); got != 5 {
+		t.Errorf("got HTTP_CLIENT_TIMEOUT %d, want 5 after reload", got)
+	}
+	if viper.IsSet("HTTP_CLIENT_BASE_URL") {
+		t.Errorf("got HTTP_CLIENT_BASE_URL %q, want it cleared", viper.GetString("HTTP_CLIENT_BASE_URL"))
+	}
+}
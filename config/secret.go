@@ -0,0 +1,248 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// SecretScheme is the prefix of the secret references, such as secret://file/run/secrets/db-password
+const SecretScheme = "secret://"
+
+// Names of the built-in secret providers
+const (
+	// FileSecretProvider reads the file of the path, such as secret://file/run/secrets/db-password
+	FileSecretProvider = "file"
+	// EncryptedSecretProvider reads the secret from the encrypted file of SECRETS_FILE,
+	// which is decrypted with the base64 key of the env var SECRETS_KEY, such as secret://encrypted/db-password
+	EncryptedSecretProvider = "encrypted"
+)
+
+// ErrSecretNotFound is returned when the provider has no secret of the name
+var ErrSecretNotFound = errors.New("config: secret not found")
+
+// SecretProvider gets the secrets by names, such as from a secret manager
+type SecretProvider interface {
+	GetSecret(name string) (string, error)
+}
+
+// SecretProviderFunc is an adapter to use a function as SecretProvider
+type SecretProviderFunc func(name string) (string, error)
+
+// GetSecret implements SecretProvider
+func (f SecretProviderFunc) GetSecret(name string) (string, error) {
+	return f(name)
+}
+
+var secretProviders = struct {
+	sync.RWMutex
+	providers map[string]SecretProvider
+}{providers: map[string]SecretProvider{
+	FileSecretProvider: SecretProviderFunc(func(name string) (string, error) {
+		// The name is the absolute path without the leading slash
+		return readSecretFile("/" + name)
+	}),
+	EncryptedSecretProvider: &envEncryptedProvider{},
+}}
+
+// RegisterSecretProvider to register the provider of the references like secret://<name>/..., the built-in
+// providers can be replaced
+// Example:
+//
+// 		config.RegisterSecretProvider("vault", config.SecretProviderFunc(func(name string) (string, error) {
+// 			return vaultClient.Read(name)
+// 		}))
+// 		// DB_PASSWORD=secret://vault/database/mysql
+//
+func RegisterSecretProvider(name string, provider SecretProvider) {
+	secretProviders.Lock()
+	defer secretProviders.Unlock()
+	secretProviders.providers[name] = provider
+}
+
+// IsSecretReference to check whether the value is a reference like secret://<provider>/<name>
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, SecretScheme)
+}
+
+// ResolveSecret to get the secret, which is
+//
+// 		the content of file if value is empty and file is not, such as a mounted secret of Kubernetes
+// 		got from the provider if value is a reference like secret://<provider>/<name>
+// 		value itself otherwise
+//
+func ResolveSecret(value, file string) (string, error) {
+	if value == "" && file != "" {
+		return readSecretFile(file)
+	}
+	if !IsSecretReference(value) {
+		return value, nil
+	}
+
+	ref := strings.TrimPrefix(value, SecretScheme)
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", fmt.Errorf("config: invalid secret reference %q, it should be %s<provider>/<name>", value, SecretScheme)
+	}
+	secretProviders.RLock()
+	provider, ok := secretProviders.providers[parts[0]]
+	secretProviders.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("config: unknown secret provider %q", parts[0])
+	}
+	secret, err := provider.GetSecret(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("config: get secret %s error: %w", value, err)
+	}
+	return secret, nil
+}
+
+// ResolveSecretKey to get the secret of the viper key, the file of the key with suffix _FILE is read if the key is not set
+// Example:
+//
+// 		// DB_PASSWORD_FILE=/run/secrets/db-password
+// 		password, err := config.ResolveSecretKey("DB_PASSWORD")
+//
+func ResolveSecretKey(key string) (string, error) {
+	return ResolveSecret(viper.GetString(key), viper.GetString(key+"_FILE"))
+}
+
+// readSecretFile to read the file, the trailing line break is removed
+func readSecretFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("config: read secret file error: %w", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// EncryptedFileProvider gets the secrets from a file encrypted by EncryptSecrets
+// The file is read and decrypted once on the first use.
+type EncryptedFileProvider struct {
+	path    string
+	key     []byte
+	once    sync.Once
+	secrets map[string]string
+	err     error
+}
+
+// NewEncryptedFileProvider to get a provider of the encrypted file, the key is 16, 24 or 32 bytes for AES-128, AES-192 or AES-256
+func NewEncryptedFileProvider(path string, key []byte) *EncryptedFileProvider {
+	return &EncryptedFileProvider{path: path, key: key}
+}
+
+// GetSecret implements SecretProvider
+func (p *EncryptedFileProvider) GetSecret(name string) (string, error) {
+	p.once.Do(func() {
+		var data []byte
+		if data, p.err = ioutil.ReadFile(p.path); p.err != nil {
+			p.err = fmt.Errorf("config: read encrypted secrets error: %w", p.err)
+			return
+		}
+		p.secrets, p.err = DecryptSecrets(data, p.key)
+	})
+	if p.err != nil {
+		return "", p.err
+	}
+	secret, ok := p.secrets[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return secret, nil
+}
+
+// envEncryptedProvider is the encrypted file provider of SECRETS_FILE and SECRETS_KEY
+// The key is only read from the env var, so that it is not kept with the config files.
+type envEncryptedProvider struct {
+	once     sync.Once
+	provider *EncryptedFileProvider
+	err      error
+}
+
+func (p *envEncryptedProvider) GetSecret(name string) (string, error) {
+	p.once.Do(func() {
+		path := viper.GetString("SECRETS_FILE")
+		if path == "" {
+			path = os.Getenv("SECRETS_FILE")
+		}
+		if path == "" {
+			p.err = errors.New("config: SECRETS_FILE is not set")
+			return
+		}
+		key, err := base64.StdEncoding.DecodeString(os.Getenv("SECRETS_KEY"))
+		if err != nil || len(key) == 0 {
+			p.err = errors.New("config: SECRETS_KEY should be a base64 encoded key")
+			return
+		}
+		p.provider = NewEncryptedFileProvider(path, key)
+	})
+	if p.err != nil {
+		return "", p.err
+	}
+	return p.provider.GetSecret(name)
+}
+
+// EncryptSecrets to encrypt the secrets with AES-GCM, the result is the content of the file of EncryptedFileProvider
+// The secrets are encoded as JSON, then encrypted and encoded as base64 with the nonce at the front.
+func EncryptSecrets(secrets map[string]string, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nonce, nonce, plain, nil)
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(out, sealed)
+	return append(out, '\n'), nil
+}
+
+// DecryptSecrets to decrypt the content encrypted by EncryptSecrets
+func DecryptSecrets(data, key []byte) (map[string]string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("config: decode encrypted secrets error: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("config: encrypted secrets are too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("config: decrypt secrets error, the key may be wrong")
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("config: decode secrets error: %w", err)
+	}
+	return secrets, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("config: invalid secret key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
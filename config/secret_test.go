@@ -0,0 +1,163 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptSecrets(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	secrets := map[string]string{"db-password": "s3cret", "api-key": "k"}
+	data, err := EncryptSecrets(secrets, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Fatalf("got the plain secret in %q", data)
+	}
+
+	got, err := DecryptSecrets(data, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["db-password"] != "s3cret" || got["api-key"] != "k" {
+		t.Errorf("got secrets %v, want %v", got, secrets)
+	}
+
+	// The nonce is random, so the same secrets are encrypted differently
+	if again, _ := EncryptSecrets(secrets, key); string(again) == string(data) {
+		t.Error("got the same encrypted content, want a new nonce")
+	}
+
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	sealed[len(sealed)-1] ^= 1
+	tampered := []byte(base64.StdEncoding.EncodeToString(sealed))
+	tests := []struct {
+		name string
+		data []byte
+		key  []byte
+	}{
+		{"wrong key", data, []byte("fedcba9876543210fedcba9876543210")},
+		{"tampered", tampered, key},
+		{"invalid key size", data, []byte("short")},
+		{"not base64", []byte("not base64!"), key},
+		{"too short", []byte(base64.StdEncoding.EncodeToString([]byte("abc"))), key},
+	}
+	for _, tt := range tests {
+		if _, err := DecryptSecrets(tt.data, tt.key); err == nil {
+			t.Errorf("%s: got no error", tt.name)
+		}
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "password")
+	if err := ioutil.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	errVault := errors.New("vault is sealed")
+	RegisterSecretProvider("test", SecretProviderFunc(func(name string) (string, error) {
+		switch name {
+		case "db/password":
+			return "from-provider", nil
+		case "sealed":
+			return "", errVault
+		}
+		return "", ErrSecretNotFound
+	}))
+	defer func() {
+		secretProviders.Lock()
+		delete(secretProviders.providers, "test")
+		secretProviders.Unlock()
+	}()
+
+	tests := []struct {
+		value   string
+		file    string
+		want    string
+		wantErr error
+	}{
+		{"plain", "", "plain", nil},
+		{"plain", file, "plain", nil},
+		{"", file, "from-file", nil},
+		{"", "", "", nil},
+		{"secret://file" + file, "", "from-file", nil},
+		{"secret://test/db/password", "", "from-provider", nil},
+		{"secret://test/missing", "", "", ErrSecretNotFound},
+		{"secret://test/sealed", "", "", errVault},
+	}
+	for _, tt := range tests {
+		got, err := ResolveSecret(tt.value, tt.file)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q %q: got %q and error %v, want %q and %v", tt.value, tt.file, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	for _, value := range []string{"secret://test", "secret://test/", "secret://unknown/name", "secret://file/nonexistent/file"} {
+		if _, err := ResolveSecret(value, ""); err == nil {
+			t.Errorf("%q: got no error", value)
+		}
+	}
+	if _, err := ResolveSecret("", filepath.Join(dir, "nonexistent")); err == nil {
+		t.Error("got no error, want the missing file")
+	}
+}
+
+func TestEncryptedProviders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	key := []byte("0123456789abcdef")
+	data, err := EncryptSecrets(map[string]string{"db-password": "s3cret"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "secrets.enc")
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := NewEncryptedFileProvider(file, key)
+	if got, err := provider.GetSecret("db-password"); got != "s3cret" || err != nil {
+		t.Errorf("got %q and error %v, want s3cret", got, err)
+	}
+	if _, err := provider.GetSecret("missing"); err != ErrSecretNotFound {
+		t.Errorf("got error %v, want ErrSecretNotFound", err)
+	}
+	if _, err := NewEncryptedFileProvider(file, []byte("fedcba9876543210")).GetSecret("db-password"); err == nil {
+		t.Error("got no error, want the wrong key")
+	}
+	if _, err := NewEncryptedFileProvider(filepath.Join(dir, "nonexistent"), key).GetSecret("db-password"); err == nil {
+		t.Error("got no error, want the missing file")
+	}
+
+	// The provider of secret://encrypted/... reads SECRETS_FILE and SECRETS_KEY
+	_ = os.Setenv("SECRETS_FILE", file)
+	_ = os.Setenv("SECRETS_KEY", base64.StdEncoding.EncodeToString(key))
+	defer os.Unsetenv("SECRETS_FILE")
+	defer os.Unsetenv("SECRETS_KEY")
+	if got, err := (&envEncryptedProvider{}).GetSecret("db-password"); got != "s3cret" || err != nil {
+		t.Errorf("got %q and error %v, want s3cret", got, err)
+	}
+	_ = os.Setenv("SECRETS_KEY", "not base64!")
+	if _, err := (&envEncryptedProvider{}).GetSecret("db-password"); err == nil {
+		t.Error("got no error, want the invalid SECRETS_KEY")
+	}
+	_ = os.Unsetenv("SECRETS_FILE")
+	if _, err := (&envEncryptedProvider{}).GetSecret("db-password"); err == nil {
+		t.Error("got no error, want the missing SECRETS_FILE")
+	}
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/uhhc/sdk-common-go/log"
+)
+
+// reloadDelay merges the events of one change, since editors and Kubernetes write a file in several steps
+const reloadDelay = 100 * time.Millisecond
+
+// Watcher reloads the config when the files change, and notifies the subscribers of the changed keys
+// A reload is rejected as a whole if the config is invalid, the current config and the subscribers are
+// not touched then.
+type Watcher struct {
+	loader  *Loader
+	typ     reflect.Type
+	current atomic.Value
+	values  map[string]interface{}
+
+	// mu serializes the loading of the reloads and the subscriptions, the subscribers are called without it
+	mu   sync.Mutex
+	subs []subscription
+	// generation is increased by every reload which changes the config
+	generation uint64
+
+	fsWatcher *fsnotify.Watcher
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type subscription struct {
+	keys []string
+	fn   func(cfg interface{})
+	// valuesFn is called with the values of the keys instead of fn
+	valuesFn func(values map[string]interface{})
+}
+
+// Watch to load the config into out, and reload it when the files change
+// out is only written by the first load, the reloaded config is a new value of the same type,
+// which is got by Current or passed to the subscribers.
+// Example:
+//
+// 		var cfg AppConfig
+// 		w, err := config.NewLoader(config.WithFile("config.yaml"), config.WithLogger(logger)).Watch(&cfg)
+// 		if err != nil {
+// 			panic(err)
+// 		}
+// 		defer w.Close()
+//
+// 		w.WatchLevel(logger, "log.level")
+// 		w.Subscribe(func(c interface{}) {
+// 			server.SetRateLimit(c.(*AppConfig).RateLimit)
+// 		}, "rate_limit")
+//
+// Only the objects changed by the subscribers use the reloaded config, see WatchLevel,
+// httpclient.DynamicTimeout.Watch and db.DbClient.WatchPool. With WithGlobal, the global viper is
+// updated by the reloads too, which applies to the clients created from it afterwards.
+func (l *Loader) Watch(out interface{}) (*Watcher, error) {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: out should be a pointer to a struct, not %T", out)
+	}
+	values, err := l.load(rv.Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		loader: l,
+		typ:    rv.Elem().Type(),
+		values: values,
+		done:   make(chan struct{}),
+	}
+	// Keep a copy, so that out can be changed by the caller
+	current := reflect.New(w.typ)
+	current.Elem().Set(rv.Elem())
+	w.current.Store(current.Interface())
+
+	if len(l.files) > 0 {
+		if w.fsWatcher, err = fsnotify.NewWatcher(); err != nil {
+			return nil, fmt.Errorf("config: watch files error: %w", err)
+		}
+		// Watch the directories, since the files may be replaced, such as the ConfigMaps of Kubernetes
+		dirs := map[string]bool{}
+		for _, file := range l.files {
+			dir := filepath.Dir(file)
+			if dirs[dir] {
+				continue
+			}
+			dirs[dir] = true
+			if err := w.fsWatcher.Add(dir); err != nil {
+				_ = w.fsWatcher.Close()
+				return nil, fmt.Errorf("config: watch %s error: %w", dir, err)
+			}
+		}
+		go w.run()
+	}
+	return w, nil
+}
+
+// Current to get the current config, which is a pointer to the type of out passed to Watch
+// It should not be modified since it is shared.
+func (w *Watcher) Current() interface{} {
+	return w.current.Load()
+}
+
+// Subscribe to call fn with the new config after a reload which changes any of the keys
+// A key of a section, such as "db", matches all keys in the section, fn is called on every change if
+// there is no key. The subscribers are called one by one in the order they subscribed.
+func (w *Watcher) Subscribe(fn func(cfg interface{}), keys ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, subscription{keys: keys, fn: fn})
+}
+
+// SubscribeValues to call fn with the values of the keys after a reload which changes any of them
+// The keys are the full keys of the fields, such as "db.max_open_conns", and the values have the types
+// of the fields. It is used by the SDK packages to apply the reloads to the live objects without
+// knowing the type of the config, see WatchLevel, httpclient.DynamicTimeout.Watch and db.DbClient.WatchPool.
+func (w *Watcher) SubscribeValues(fn func(values map[string]interface{}), keys ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, subscription{keys: keys, valuesFn: fn})
+}
+
+// WatchLevel to change the level of the logger when the key is reloaded, such as "log.level"
+// Example:
+//
+// 		w, err := config.NewLoader(config.WithFile("config.yaml")).Watch(&cfg)
+// 		logger := log.NewLogger(&cfg.Log)
+// 		w.WatchLevel(logger, "log.level")
+//
+func (w *Watcher) WatchLevel(logger *log.Logger, key string) {
+	w.SubscribeValues(func(values map[string]interface{}) {
+		level, _ := values[key].(string)
+		if level == "" {
+			return
+		}
+		if err := logger.SetLevel(level); err != nil {
+			w.logError("set log level error", err)
+		}
+	}, key)
+}
+
+// Reload to load the config again, such as on SIGHUP
+// The config is kept if the error is not nil. The subscribers are called after the lock is released, so they
+// can call Subscribe or Reload. If another reload changes the config meanwhile, the rest of the subscribers
+// are notified by that one only, so that none of them ends with an older config.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	next := reflect.New(w.typ)
+	values, err := w.loader.load(next.Elem())
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	changed := changedKeys(w.values, values)
+	if len(changed) == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	w.values = values
+	w.current.Store(next.Interface())
+	generation := atomic.AddUint64(&w.generation, 1)
+	subs := append([]subscription(nil), w.subs...)
+	w.mu.Unlock()
+	w.logInfo("config reloaded", changed)
+
+	cfg := next.Interface()
+	for _, sub := range subs {
+		if atomic.LoadUint64(&w.generation) != generation {
+			return nil
+		}
+		if !sub.matches(changed) {
+			continue
+		}
+		if sub.valuesFn != nil {
+			sub.valuesFn(sub.valuesOf(values))
+			continue
+		}
+		sub.fn(cfg)
+	}
+	return nil
+}
+
+// Close to stop watching the files
+func (w *Watcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		if w.fsWatcher != nil {
+			err = w.fsWatcher.Close()
+		}
+	})
+	return err
+}
+
+func (w *Watcher) run() {
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fsWatcher.Events:
+			if !ok {
+				return
+			}
+			if w.isConfigEvent(event) {
+				timer.Reset(reloadDelay)
+			}
+		case err, ok := <-w.fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			w.logError("watch config files error", err)
+		case <-timer.C:
+			if err := w.Reload(); err != nil {
+				w.logError("reload config error, the config is not changed", err)
+			}
+		}
+	}
+}
+
+// isConfigEvent to check whether the event changes a config file, or a symlink in the directory,
+// such as "..data" of the ConfigMaps of Kubernetes
+func (w *Watcher) isConfigEvent(event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+		return false
+	}
+	name := filepath.Clean(event.Name)
+	for _, file := range w.loader.files {
+		if filepath.Clean(file) == name {
+			return true
+		}
+	}
+	return strings.HasPrefix(filepath.Base(name), "..")
+}
+
+func (w *Watcher) logInfo(msg string, changed []string) {
+	if w.loader.logger != nil {
+		w.loader.logger.Infow(msg, "changed", changed)
+	}
+}
+
+func (w *Watcher) logError(msg string, err error) {
+	if w.loader.logger != nil {
+		w.loader.logger.Errorw(msg, "error", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "config: %s: %v\n", msg, err)
+}
+
+func (s subscription) matches(changed []string) bool {
+	if len(s.keys) == 0 {
+		return true
+	}
+	for _, key := range s.keys {
+		for _, c := range changed {
+			if c == key || strings.HasPrefix(c, key+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s subscription) valuesOf(values map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(s.keys))
+	for _, key := range s.keys {
+		if v, ok := values[key]; ok {
+			m[key] = v
+		}
+	}
+	return m
+}
+
+// changedKeys to get the sorted keys whose values are different
+func changedKeys(old, new map[string]interface{}) []string {
+	var changed []string
+	for key, v := range new {
+		if ov, ok := old[key]; !ok || !reflect.DeepEqual(ov, v) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/uhhc/sdk-common-go/log"
+)
+
+type watchConfig struct {
+	Log  log.Option `config:"log"`
+	Pool struct {
+		MaxOpenConns int `config:"max_open_conns"`
+		MaxIdleConns int `config:"max_idle_conns"`
+	} `config:"pool"`
+}
+
+// newWatchFile to get a config file in a temporary directory, and the function to write it
+func newWatchFile(t *testing.T) (string, func(content string), func()) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "config.yaml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return file, write, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestWatchLevel(t *testing.T) {
+	file, write, cleanup := newWatchFile(t)
+	defer cleanup()
+	write("log:\n  level: info\n")
+
+	var cfg watchConfig
+	w, err := NewLoader(WithFile(file)).Watch(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	logger := log.NewLogger(&log.Option{LogLevel: cfg.Log.LogLevel, OutputPaths: []string{os.DevNull}})
+	w.WatchLevel(logger, "log.level")
+
+	write("log:\n  level: warn\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := logger.AtomicLevel.Level().String(); got != "warn" {
+		t.Errorf("got level %s, want warn", got)
+	}
+
+	// An invalid level rejects the reload, the level is not changed
+	write("log:\n  level: verbose\n")
+	if err := w.Reload(); err == nil {
+		t.Error("got no error, want the invalid level")
+	}
+	if got := logger.AtomicLevel.Level().String(); got != "warn" {
+		t.Errorf("got level %s, want warn", got)
+	}
+}
+
+func TestSubscribeValues(t *testing.T) {
+	file, write, cleanup := newWatchFile(t)
+	defer cleanup()
+	write("pool:\n  max_open_conns: 10\n  max_idle_conns: 2\n")
+
+	var cfg watchConfig
+	w, err := NewLoader(WithFile(file)).Watch(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	var got []map[string]interface{}
+	w.SubscribeValues(func(values map[string]interface{}) {
+		got = append(got, values)
+	}, "pool.max_open_conns", "pool.max_idle_conns")
+
+	// Other keys do not notify the subscriber
+	write("pool:\n  max_open_conns: 10\n  max_idle_conns: 2\nlog:\n  level: error\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	write("pool:\n  max_open_conns: 20\n  max_idle_conns: 2\nlog:\n  level: error\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(got))
+	}
+	if got[0]["pool.max_open_conns"] != 20 || got[0]["pool.max_idle_conns"] != 2 {
+		t.Errorf("got values %v, want both keys with the new values", got[0])
+	}
+}
+
+func TestWatchGlobal(t *testing.T) {
+	file, write, cleanup := newWatchFile(t)
+	defer cleanup()
+	write("log:\n  level: info\n")
+
+	var cfg watchConfig
+	w, err := NewLoader(WithFile(file), WithGlobal()).Watch(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if got := viper.GetString("LOG_LEVEL"); got != "info" {
+		t.Errorf("got LOG_LEVEL %q, want info", got)
+	}
+
+	write("log:\n  level: error\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := viper.GetString("LOG_LEVEL"); got != "error" {
+		t.Errorf("got LOG_LEVEL %q after reload, want error", got)
+	}
+}
+
+func TestSubscriberCallsWatcher(t *testing.T) {
+	file, write, cleanup := newWatchFile(t)
+	defer cleanup()
+	write("pool:\n  max_open_conns: 10\n")
+
+	var cfg watchConfig
+	w, err := NewLoader(WithFile(file)).Watch(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// The first subscriber changes the file again and reloads it, the second one only sees the newest config
+	reloaded := false
+	w.Subscribe(func(c interface{}) {
+		w.Subscribe(func(interface{}) {}, "log")
+		if !reloaded {
+			reloaded = true
+			write("pool:\n  max_open_conns: 30\n")
+			if err := w.Reload(); err != nil {
+				t.Error(err)
+			}
+		}
+	}, "pool")
+	var seen []int
+	w.Subscribe(func(c interface{}) {
+		seen = append(seen, c.(*watchConfig).Pool.MaxOpenConns)
+	}, "pool")
+
+	done := make(chan error, 1)
+	go func() {
+		write("pool:\n  max_open_conns: 20\n")
+		done <- w.Reload()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("got Reload blocked by the subscribers calling the watcher")
+	}
+	if len(seen) != 1 || seen[0] != 30 {
+		t.Errorf("got configs %v, want only the newest 30", seen)
+	}
+	if got := w.Current().(*watchConfig).Pool.MaxOpenConns; got != 30 {
+		t.Errorf("got current %d, want 30", got)
+	}
+}
@@ -1,28 +1,54 @@
 package db
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"net"
+	"time"
 
-	// use mysql library
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
 	"github.com/spf13/viper"
 
+	sdkconfig "github.com/uhhc/sdk-common-go/config"
 	"github.com/uhhc/sdk-common-go/log"
+	"github.com/uhhc/sdk-common-go/util/backoff"
 )
 
 // Config is the config for db connection
+// The config tags are the keys of the config package, such as "db.host" for the section "db".
 type Config struct {
-	Engine   string
-	User     string
-	Password string
-	DBName   string
-	Host     string
-	Port     string
-	Charset  string
+	Engine   string `config:"engine" default:"mysql"`
+	User     string `config:"user"`
+	Password string `config:"password"`
+	DBName   string `config:"name"`
+	Host     string `config:"host" required:"true"`
+	Port     string `config:"port" default:"3306"`
+	Charset  string `config:"charset" default:"utf8mb4"`
+	// UserFile and PasswordFile are read if User and Password are empty, such as the mounted secrets of Kubernetes
+	// User and Password can also be references like secret://<provider>/<name> of the config package.
+	UserFile     string `config:"user_file"`
+	PasswordFile string `config:"password_file"`
+	// ConnTimeout is the timeout of every connect attempt in seconds, DB_CONN_TIMEOUT is used if it is 0
+	ConnTimeout uint32 `config:"conn_timeout" default:"10"`
+
+	// Startup retry settings, the intervals are in seconds
+	ConnectRetries          int   `config:"connect_retries"`
+	ConnectRetryInterval    int64 `config:"connect_retry_interval"`
+	ConnectRetryMaxInterval int64 `config:"connect_retry_max_interval"`
+	ConnectMaxElapsedTime   int64 `config:"connect_max_elapsed_time"`
+
+	// Connection pool settings, they can be changed by SetPool while the client is in use
+	// MaxOpenConns is 0 for no limit, MaxIdleConns is 0 for the default of database/sql,
+	// and ConnMaxLifetime is in seconds, 0 for reusing the connections forever.
+	MaxOpenConns    int   `config:"max_open_conns"`
+	MaxIdleConns    int   `config:"max_idle_conns"`
+	ConnMaxLifetime int64 `config:"conn_max_lifetime"`
 }
 
+// defaultMaxIdleConns is the default of database/sql
+const defaultMaxIdleConns = 2
+
 // DbClient is the struct of db client
 type DbClient struct {
 	*gorm.DB
@@ -35,27 +61,57 @@ func NewDB(logger log.Logger, config *Config) (*DbClient, error) {
 		db                                                  *gorm.DB
 		err                                                 error
 		engine, user, password, dbName, host, port, charset string
+		userFile, passwordFile                              string
 		timeout                                             uint32
+		retry                                               *backoff.Policy
+		pool                                                *Config
 	)
 
 	if config == nil {
 		engine = viper.GetString("DB_ENGINE")
 		user = viper.GetString("DB_USER")
 		password = viper.GetString("DB_PASSWORD")
+		userFile = viper.GetString("DB_USER_FILE")
+		passwordFile = viper.GetString("DB_PASSWORD_FILE")
 		dbName = viper.GetString("DB_NAME")
 		host = viper.GetString("DB_HOST")
 		port = viper.GetString("DB_PORT")
 		charset = viper.GetString("DB_CHARSET")
+		retry = &backoff.Policy{
+			Retries:         viper.GetInt("DB_CONNECT_RETRIES"),
+			InitialInterval: time.Duration(viper.GetInt64("DB_CONNECT_RETRY_INTERVAL")) * time.Second,
+			MaxInterval:     time.Duration(viper.GetInt64("DB_CONNECT_RETRY_MAX_INTERVAL")) * time.Second,
+			MaxElapsedTime:  time.Duration(viper.GetInt64("DB_CONNECT_MAX_ELAPSED_TIME")) * time.Second,
+			Jitter:          backoff.DefaultJitter,
+		}
+		pool = &Config{
+			MaxOpenConns:    viper.GetInt("DB_MAX_OPEN_CONNS"),
+			MaxIdleConns:    viper.GetInt("DB_MAX_IDLE_CONNS"),
+			ConnMaxLifetime: viper.GetInt64("DB_CONN_MAX_LIFETIME"),
+		}
 	} else {
 		engine = config.Engine
 		user = config.User
 		password = config.Password
+		userFile = config.UserFile
+		passwordFile = config.PasswordFile
 		dbName = config.DBName
 		host = config.Host
 		port = config.Port
 		charset = config.Charset
+		retry = &backoff.Policy{
+			Retries:         config.ConnectRetries,
+			InitialInterval: time.Duration(config.ConnectRetryInterval) * time.Second,
+			MaxInterval:     time.Duration(config.ConnectRetryMaxInterval) * time.Second,
+			MaxElapsedTime:  time.Duration(config.ConnectMaxElapsedTime) * time.Second,
+			Jitter:          backoff.DefaultJitter,
+		}
+		timeout = config.ConnTimeout
+		pool = config
+	}
+	if timeout == 0 {
+		timeout = viper.GetUint32("DB_CONN_TIMEOUT")
 	}
-	timeout = viper.GetUint32("DB_CONN_TIMEOUT")
 	if timeout == 0 {
 		timeout = 10
 	}
@@ -64,18 +120,114 @@ func NewDB(logger log.Logger, config *Config) (*DbClient, error) {
 		charset = "utf8mb4"
 	}
 
+	// Resolve the credentials from the files or secret providers
+	if user, err = sdkconfig.ResolveSecret(user, userFile); err == nil {
+		password, err = sdkconfig.ResolveSecret(password, passwordFile)
+	}
+	if err != nil {
+		logger.Errorw("resolve db credentials error", "error", err)
+		return &DbClient{logger: logger}, err
+	}
+
 	if engine == "mysql" {
 		// See https://gorm.io/docs/connecting_to_the_database.html
-		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=Local&timeout=%ds", user, password, host, port, dbName, charset, timeout)
-		// fmt.Printf("dsn: %+v\n", dsn)
-		db, err = gorm.Open(engine, dsn)
+		dsn := mysqlDSN(user, password, host, port, dbName, charset, timeout)
+		err = retry.Retry(context.Background(), func() error {
+			var openErr error
+			db, openErr = gorm.Open(engine, dsn)
+			return openErr
+		}, func(attempt int, err error, wait time.Duration) {
+			logger.Warnw("connect to db error, will retry", "error", err, "attempt", attempt, "wait", wait.String())
+		})
+		if err != nil {
+			logger.Errorw("connect to db error", "error", err, "host", host, "port", port)
+		}
 	} else {
 		db = nil
 		err = errors.New(engine + " is an unsupported database engine")
 	}
 
-	return &DbClient{
+	client := &DbClient{
 		DB:     db,
 		logger: logger,
-	}, err
+	}
+	if err == nil {
+		client.SetPool(pool)
+	}
+	return client, err
+}
+
+// mysqlDSN to build the DSN of the mysql driver, the credentials are escaped by the driver
+func mysqlDSN(user, password, host, port, dbName, charset string, timeout uint32) string {
+	cfg := mysql.NewConfig()
+	cfg.User = user
+	cfg.Passwd = password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(host, port)
+	cfg.DBName = dbName
+	cfg.Params = map[string]string{"charset": charset}
+	cfg.ParseTime = true
+	cfg.Loc = time.Local
+	cfg.Timeout = time.Duration(timeout) * time.Second
+	return cfg.FormatDSN()
+}
+
+// Ping to check the connection to the database, such as for the health checks
+func (c *DbClient) Ping(ctx context.Context) error {
+	if c.DB == nil {
+		return errors.New("db is not connected")
+	}
+	return c.DB.DB().PingContext(ctx)
+}
+
+// SetPool to apply the connection pool settings of config, other settings are ignored
+// It is safe to call while the client is in use, such as when the config is reloaded.
+func (c *DbClient) SetPool(config *Config) {
+	if c.DB == nil || config == nil {
+		return
+	}
+	sqlDB := c.DB.DB()
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	if config.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	} else {
+		sqlDB.SetMaxIdleConns(defaultMaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(time.Duration(config.ConnMaxLifetime) * time.Second)
+}
+
+// WatchPool to set the connection pool when the pool keys of the section are reloaded by the watcher,
+// such as "db.max_open_conns" for the section "db"
+// Example:
+//
+// 		w, err := config.NewLoader(config.WithFile("config.yaml")).Watch(&cfg)
+// 		dbClient, err := db.NewDB(*logger, &cfg.DB)
+// 		dbClient.WatchPool(w, "db")
+//
+func (c *DbClient) WatchPool(w *sdkconfig.Watcher, section string) {
+	var (
+		maxOpenConns    = section + ".max_open_conns"
+		maxIdleConns    = section + ".max_idle_conns"
+		connMaxLifetime = section + ".conn_max_lifetime"
+	)
+	w.SubscribeValues(func(values map[string]interface{}) {
+		pool := &Config{}
+		pool.MaxOpenConns, _ = values[maxOpenConns].(int)
+		pool.MaxIdleConns, _ = values[maxIdleConns].(int)
+		pool.ConnMaxLifetime, _ = values[connMaxLifetime].(int64)
+		c.SetPool(pool)
+	}, maxOpenConns, maxIdleConns, connMaxLifetime)
+}
+
+// WithContext to get a copy of the client which logs with the logger and request-scoped fields of ctx
+// Example:
+//
+// 		repo := dbClient.WithContext(ctx).Repository(&User{})
+//
+func (c *DbClient) WithContext(ctx context.Context) *DbClient {
+	logger := log.ContextLogger(ctx, &c.logger)
+	return &DbClient{
+		DB:     c.DB,
+		logger: *logger,
+	}
 }
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+
+	"github.com/uhhc/sdk-common-go/config"
+)
+
+func TestWatchPool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.yaml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("db:\n  host: localhost\n  max_open_conns: 10\n")
+
+	var cfg struct {
+		DB Config `config:"db"`
+	}
+	w, err := config.NewLoader(config.WithFile(file)).Watch(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// The pool is set without connecting to the database
+	sqlDB, err := sql.Open("mysql", "user:password@tcp(127.0.0.1:1)/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	gormDB, _ := gorm.Open("mysql", sqlDB)
+	client := &DbClient{DB: gormDB}
+	client.SetPool(&cfg.DB)
+	client.WatchPool(w, "db")
+
+	write("db:\n  host: localhost\n  max_open_conns: 20\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := sqlDB.Stats().MaxOpenConnections; got != 20 {
+		t.Errorf("got max open connections %d, want 20", got)
+	}
+}
+
+func TestMySQLDSN(t *testing.T) {
+	dsn := mysqlDSN("app", "p@ss:w/rd?&=", "db.local", "3307", "orders", "utf8mb4", 5)
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("parse %q error: %v", dsn, err)
+	}
+	if cfg.User != "app" || cfg.Passwd != "p@ss:w/rd?&=" || cfg.Addr != "db.local:3307" || cfg.DBName != "orders" {
+		t.Errorf("got user %q, password %q, address %q and db %q", cfg.User, cfg.Passwd, cfg.Addr, cfg.DBName)
+	}
+	if cfg.Params["charset"] != "utf8mb4" || !cfg.ParseTime || cfg.Loc != time.Local || cfg.Timeout != 5*time.Second {
+		t.Errorf("got params %v, parseTime %v, loc %s and timeout %s", cfg.Params, cfg.ParseTime, cfg.Loc, cfg.Timeout)
+	}
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrNotFound is returned when the record can not be found
+var ErrNotFound = errors.New("record not found")
+
+// ValidationError is returned when the input of an operation is invalid
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+// Error implements error interface
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return "validation error: " + e.Message
+	}
+	return fmt.Sprintf("validation error: %s %s", e.Field, e.Message)
+}
+
+// Validator can be implemented by models to be validated before they are written
+type Validator interface {
+	Validate() error
+}
+
+// IsNotFound to check whether the error means the record can not be found
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || gorm.IsRecordNotFoundError(err)
+}
+
+// IsValidationError to check whether the error is a validation error
+func IsValidationError(err error) bool {
+	var ve *ValidationError
+	return errors.As(err, &ve)
+}
+
+func validate(value interface{}) error {
+	v, ok := value.(Validator)
+	if !ok {
+		return nil
+	}
+	err := v.Validate()
+	if err == nil || IsValidationError(err) {
+		return err
+	}
+	return &ValidationError{Message: err.Error()}
+}
+
+func convertError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrNotFound
+	}
+	return err
+}
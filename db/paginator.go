@@ -0,0 +1,235 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/uhhc/sdk-common-go/types/pagination"
+)
+
+// PageOptions represents the options of a paginated query
+type PageOptions struct {
+	// Page starts from 1, it is ignored in keyset mode
+	Page int
+	Size int
+	// Sort is a list of columns with an optional direction, such as "created_at desc".
+	// In keyset mode it is required and the last column must be unique, such as the primary key.
+	Sort []string
+	// Keyset enables keyset mode, the next page is located by Cursor instead of OFFSET
+	Keyset bool
+	// Cursor is the NextCursor of the previous page, empty means the first page
+	Cursor string
+	// WithTotal counts the total amount in keyset mode, it is always counted in offset mode
+	WithTotal bool
+}
+
+// Paginator paginates the results of a gorm query
+type Paginator struct {
+	query *gorm.DB
+}
+
+type sortKey struct {
+	column string
+	desc   bool
+}
+
+// NewPaginator to get a paginator of the given query
+// Example:
+//
+// 		var users []User
+// 		query := dbClient.Model(&User{}).Where("status = ?", 1)
+//
+// 		// Offset mode
+// 		page, err := db.NewPaginator(query).Paginate(&users, &db.PageOptions{Page: 2, Size: 20})
+//
+// 		// Keyset mode, pass page.NextCursor to get the next page
+// 		page, err := db.NewPaginator(query).Paginate(&users, &db.PageOptions{
+// 			Size:   20,
+// 			Sort:   []string{"created_at desc", "id desc"},
+// 			Keyset: true,
+// 			Cursor: cursor,
+// 		})
+//
+func NewPaginator(query *gorm.DB) *Paginator {
+	return &Paginator{
+		query: query,
+	}
+}
+
+// Paginate to find one page of records into out, which must be a pointer to a slice
+func (p *Paginator) Paginate(out interface{}, opts *PageOptions) (*pagination.Page, error) {
+	if opts == nil {
+		opts = &PageOptions{}
+	}
+	if v := reflect.ValueOf(out); v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return nil, &ValidationError{Field: "out", Message: "must be a pointer to a slice"}
+	}
+	page, size, err := normalizePage(opts.Page, opts.Size)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseSort(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Keyset {
+		return p.keyset(out, keys, size, opts)
+	}
+
+	result := &pagination.Page{
+		Items: out,
+		Page:  page,
+		Size:  size,
+	}
+	if err := p.query.Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+	query := p.query
+	for _, key := range keys {
+		query = query.Order(key.String())
+	}
+	if err := query.Offset((page - 1) * size).Limit(size).Find(out).Error; err != nil {
+		return nil, err
+	}
+	result.HasMore = int64(page*size) < result.Total
+	return result, nil
+}
+
+func (p *Paginator) keyset(out interface{}, keys []sortKey, size int, opts *PageOptions) (*pagination.Page, error) {
+	if len(keys) == 0 {
+		return nil, &ValidationError{Field: "sort", Message: "is required in keyset mode"}
+	}
+	result := &pagination.Page{
+		Items: out,
+		Size:  size,
+	}
+	if opts.WithTotal {
+		if err := p.query.Count(&result.Total).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	query := p.query
+	if opts.Cursor != "" {
+		values, err := pagination.DecodeCursor(opts.Cursor)
+		if err != nil || len(values) != len(keys) {
+			return nil, &ValidationError{Field: "cursor", Message: "is invalid"}
+		}
+		where, args := keysetCondition(keys, values)
+		query = query.Where(where, args...)
+	}
+	for _, key := range keys {
+		query = query.Order(key.String())
+	}
+	// Fetch one more record to know whether there is a next page
+	if err := query.Limit(size + 1).Find(out).Error; err != nil {
+		return nil, err
+	}
+
+	items := reflect.ValueOf(out).Elem()
+	if items.Len() > size {
+		items.SetLen(size)
+		result.HasMore = true
+	}
+	if result.HasMore {
+		cursor, err := p.cursorOf(items.Index(size-1), keys)
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = cursor
+	}
+	return result, nil
+}
+
+func (p *Paginator) cursorOf(item reflect.Value, keys []sortKey) (string, error) {
+	if item.Kind() != reflect.Ptr {
+		item = item.Addr()
+	}
+	scope := p.query.NewScope(item.Interface())
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		column := key.column
+		if idx := strings.LastIndex(column, "."); idx >= 0 {
+			column = column[idx+1:]
+		}
+		field, ok := scope.FieldByName(column)
+		if !ok {
+			return "", &ValidationError{Field: "sort", Message: "column " + key.column + " is not a field of the model"}
+		}
+		// Convert the field like the driver arguments, such as dereferencing the pointers and calling
+		// driver.Valuer, so that time.Time is kept as time.Time in the cursor
+		value, err := driver.DefaultParameterConverter.ConvertValue(field.Field.Interface())
+		if err != nil {
+			return "", err
+		}
+		values[i] = value
+	}
+	return pagination.EncodeCursor(values)
+}
+
+// keysetCondition builds the condition to seek after the given values, such as
+// "(a < ?) OR (a = ? AND b > ?)" for "a desc, b asc"
+func keysetCondition(keys []sortKey, values []interface{}) (string, []interface{}) {
+	var (
+		ors  []string
+		args []interface{}
+	)
+	for i, key := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, keys[j].column+" = ?")
+			args = append(args, values[j])
+		}
+		op := ">"
+		if key.desc {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s ?", key.column, op))
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return strings.Join(ors, " OR "), args
+}
+
+func (k sortKey) String() string {
+	if k.desc {
+		return k.column + " desc"
+	}
+	return k.column + " asc"
+}
+
+func normalizePage(page, size int) (int, int, error) {
+	if page < 0 {
+		return 0, 0, &ValidationError{Field: "page", Message: "must not be negative"}
+	}
+	if size < 0 || size > MaxPageSize {
+		return 0, 0, &ValidationError{Field: "size", Message: fmt.Sprintf("must be between 1 and %d", MaxPageSize)}
+	}
+	if page == 0 {
+		page = 1
+	}
+	if size == 0 {
+		size = DefaultPageSize
+	}
+	return page, size, nil
+}
+
+func parseSort(sort []string) ([]sortKey, error) {
+	keys := make([]sortKey, 0, len(sort))
+	for _, s := range sort {
+		s = strings.TrimSpace(s)
+		if !sortPattern.MatchString(s) {
+			return nil, &ValidationError{Field: "sort", Message: "is invalid: " + s}
+		}
+		fields := strings.Fields(s)
+		keys = append(keys, sortKey{
+			column: fields[0],
+			desc:   len(fields) > 1 && strings.EqualFold(fields[1], "desc"),
+		})
+	}
+	return keys, nil
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uhhc/sdk-common-go/types/pagination"
+)
+
+func TestKeysetCondition(t *testing.T) {
+	tests := []struct {
+		keys      []sortKey
+		values    []interface{}
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			[]sortKey{{column: "id"}},
+			[]interface{}{int64(5)},
+			"(id > ?)",
+			[]interface{}{int64(5)},
+		},
+		{
+			[]sortKey{{column: "id", desc: true}},
+			[]interface{}{int64(5)},
+			"(id < ?)",
+			[]interface{}{int64(5)},
+		},
+		{
+			[]sortKey{{column: "status", desc: true}, {column: "id"}},
+			[]interface{}{int64(2), int64(5)},
+			"(status < ?) OR (status = ? AND id > ?)",
+			[]interface{}{int64(2), int64(2), int64(5)},
+		},
+		{
+			[]sortKey{{column: "u.name"}, {column: "u.status", desc: true}, {column: "u.id"}},
+			[]interface{}{"bob", int64(1), int64(3)},
+			"(u.name > ?) OR (u.name = ? AND u.status < ?) OR (u.name = ? AND u.status = ? AND u.id > ?)",
+			[]interface{}{"bob", "bob", int64(1), "bob", int64(1), int64(3)},
+		},
+	}
+	for _, tt := range tests {
+		where, args := keysetCondition(tt.keys, tt.values)
+		if where != tt.wantWhere || !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("%v: got %q %v, want %q %v", tt.keys, where, args, tt.wantWhere, tt.wantArgs)
+		}
+	}
+}
+
+// newPaginatorClient to get a client with 7 users, whose statuses are 1, 2, 3, 1, 2, 3, 1
+func newPaginatorClient(t *testing.T) *DbClient {
+	t.Helper()
+	client := newTestClient(t)
+	for i, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		if err := client.Create(&testUser{Name: name, Status: i%3 + 1}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return client
+}
+
+func userIDs(users []testUser) []uint {
+	ids := make([]uint, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func TestPaginateOffset(t *testing.T) {
+	client := newPaginatorClient(t)
+	defer client.Close()
+	query := client.Model(&testUser{}).Where("status <> ?", 3)
+
+	tests := []struct {
+		opts        *PageOptions
+		wantIDs     []uint
+		wantPage    int
+		wantHasMore bool
+	}{
+		{nil, []uint{1, 2, 4, 5, 7}, 1, false},
+		{&PageOptions{Size: 2, Sort: []string{"id desc"}}, []uint{7, 5}, 1, true},
+		{&PageOptions{Page: 2, Size: 2, Sort: []string{"id desc"}}, []uint{4, 2}, 2, true},
+		{&PageOptions{Page: 3, Size: 2, Sort: []string{"id desc"}}, []uint{1}, 3, false},
+		{&PageOptions{Page: 4, Size: 2, Sort: []string{"id desc"}}, []uint{}, 4, false},
+		{&PageOptions{Size: 3, Sort: []string{"status desc", "id"}}, []uint{2, 5, 1}, 1, true},
+	}
+	for _, tt := range tests {
+		var users []testUser
+		page, err := NewPaginator(query).Paginate(&users, tt.opts)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.opts, err)
+		}
+		if got := userIDs(users); !reflect.DeepEqual(got, tt.wantIDs) {
+			t.Errorf("%+v: got ids %v, want %v", tt.opts, got, tt.wantIDs)
+		}
+		if page.Total != 5 || page.Page != tt.wantPage || page.HasMore != tt.wantHasMore || page.NextCursor != "" {
+			t.Errorf("%+v: got page %+v", tt.opts, page)
+		}
+	}
+}
+
+func TestPaginateKeyset(t *testing.T) {
+	client := newPaginatorClient(t)
+	defer client.Close()
+
+	tests := []struct {
+		sort      []string
+		wantPages [][]uint
+	}{
+		{[]string{"id"}, [][]uint{{1, 2, 3}, {4, 5, 6}, {7}}},
+		{[]string{"id desc"}, [][]uint{{7, 6, 5}, {4, 3, 2}, {1}}},
+		{[]string{"status desc", "id"}, [][]uint{{3, 6, 2}, {5, 1, 4}, {7}}},
+		{[]string{"status", "name desc", "id desc"}, [][]uint{{7, 4, 1}, {5, 2, 6}, {3}}},
+	}
+	for _, tt := range tests {
+		var (
+			cursor string
+			pages  [][]uint
+		)
+		for i := 0; i < 5; i++ {
+			var users []testUser
+			page, err := NewPaginator(client.Model(&testUser{})).Paginate(&users, &PageOptions{
+				Size:      3,
+				Sort:      tt.sort,
+				Keyset:    true,
+				Cursor:    cursor,
+				WithTotal: i == 0,
+			})
+			if err != nil {
+				t.Fatalf("%v: %v", tt.sort, err)
+			}
+			if i == 0 && page.Total != 7 {
+				t.Errorf("%v: got total %d, want 7", tt.sort, page.Total)
+			}
+			if i > 0 && page.Total != 0 {
+				t.Errorf("%v: got total %d without WithTotal, want 0", tt.sort, page.Total)
+			}
+			if page.HasMore != (page.NextCursor != "") {
+				t.Errorf("%v: got page %+v, want the cursor only if there are more", tt.sort, page)
+			}
+			pages = append(pages, userIDs(users))
+			if !page.HasMore {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		if !reflect.DeepEqual(pages, tt.wantPages) {
+			t.Errorf("%v: got pages %v, want %v", tt.sort, pages, tt.wantPages)
+		}
+	}
+}
+
+func TestPaginateErrors(t *testing.T) {
+	client := newPaginatorClient(t)
+	defer client.Close()
+	paginator := NewPaginator(client.Model(&testUser{}))
+	otherCursor, err := pagination.EncodeCursor([]interface{}{int64(1), int64(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var users []testUser
+	tests := []struct {
+		name string
+		out  interface{}
+		opts *PageOptions
+	}{
+		{"not a pointer", users, nil},
+		{"not a slice", &testUser{}, nil},
+		{"negative page", &users, &PageOptions{Page: -1}},
+		{"negative size", &users, &PageOptions{Size: -1}},
+		{"too large size", &users, &PageOptions{Size: MaxPageSize + 1}},
+		{"invalid sort", &users, &PageOptions{Sort: []string{"id; drop table users"}}},
+		{"keyset without sort", &users, &PageOptions{Keyset: true}},
+		{"invalid cursor", &users, &PageOptions{Keyset: true, Sort: []string{"id"}, Cursor: "invalid"}},
+		{"cursor of other sort", &users, &PageOptions{Keyset: true, Sort: []string{"id"}, Cursor: otherCursor}},
+		{"column out of the model", &users, &PageOptions{Keyset: true, Size: 1, Sort: []string{"rowid"}}},
+	}
+	for _, tt := range tests {
+		if _, err := paginator.Paginate(tt.out, tt.opts); !IsValidationError(err) {
+			t.Errorf("%s: got error %v, want a validation error", tt.name, err)
+		}
+	}
+}
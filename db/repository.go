@@ -0,0 +1,258 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Default values of the list options
+const (
+	DefaultPageSize  = 20
+	MaxPageSize      = 1000
+	DefaultChunkSize = 100
+)
+
+var sortPattern = regexp.MustCompile(`^[A-Za-z0-9_.]+( +(?i:asc|desc))?$`)
+
+// ListOptions represents the filters, sort and pagination of a list query
+type ListOptions struct {
+	// Filters are column/value pairs which will be combined with AND,
+	// a slice value will be converted to IN condition
+	Filters map[string]interface{}
+	// Sort is a list of columns with an optional direction, such as "created_at desc"
+	Sort []string
+	// Page starts from 1
+	Page int
+	Size int
+}
+
+// Repository provides common CRUD operations for a gorm model
+type Repository struct {
+	client *DbClient
+	model  interface{}
+}
+
+// Repository to get a repository of the given model
+// Example:
+//
+// 		type User struct {
+// 			ID   uint
+// 			Name string
+// 		}
+// 		repo := dbClient.Repository(&User{})
+// 		var user User
+// 		err := repo.GetByID(1, &user)
+// 		if db.IsNotFound(err) {
+// 			// ...
+// 		}
+//
+func (c *DbClient) Repository(model interface{}) *Repository {
+	return &Repository{
+		client: c,
+		model:  model,
+	}
+}
+
+// Create to insert one record
+func (r *Repository) Create(value interface{}) error {
+	if err := validate(value); err != nil {
+		return err
+	}
+	if err := r.client.Create(value).Error; err != nil {
+		r.client.logger.Errorw("create record error", "method", "Create", "error", err)
+		return err
+	}
+	return nil
+}
+
+// BatchCreate to insert a slice of records, every chunk is inserted in one transaction
+// If chunkSize is not positive, DefaultChunkSize will be used.
+func (r *Repository) BatchCreate(values interface{}, chunkSize int) error {
+	v := reflect.Indirect(reflect.ValueOf(values))
+	if v.Kind() != reflect.Slice {
+		return &ValidationError{Field: "values", Message: "must be a slice"}
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	items := make([]interface{}, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		if item.Kind() != reflect.Ptr && item.CanAddr() {
+			item = item.Addr()
+		}
+		items[i] = item.Interface()
+		if err := validate(items[i]); err != nil {
+			return err
+		}
+	}
+
+	for start := 0; start < len(items); start += chunkSize {
+		end := start + chunkSize
+		if end > len(items) {
+			end = len(items)
+		}
+		err := r.transaction(func(tx *gorm.DB) error {
+			for _, item := range items[start:end] {
+				if err := tx.Create(item).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			r.client.logger.Errorw("batch create records error", "method", "BatchCreate", "error", err, "offset", start)
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByID to get one record by primary key
+func (r *Repository) GetByID(id interface{}, out interface{}) error {
+	if isBlank(id) {
+		return &ValidationError{Field: "id", Message: "is required"}
+	}
+	scope := r.client.NewScope(r.model)
+	query := fmt.Sprintf("%s.%s = ?", scope.QuotedTableName(), scope.Quote(scope.PrimaryKey()))
+	err := r.client.Model(r.model).Where(query, id).First(out).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		r.client.logger.Errorw("get record error", "method", "GetByID", "error", err, "id", id)
+	}
+	return convertError(err)
+}
+
+// List to get records with filters, sort and pagination, the total amount is returned too
+// Example:
+//
+// 		var users []User
+// 		total, err := repo.List(&users, &db.ListOptions{
+// 			Filters: map[string]interface{}{"status": []int{1, 2}},
+// 			Sort:    []string{"created_at desc"},
+// 			Page:    1,
+// 			Size:    20,
+// 		})
+//
+func (r *Repository) List(out interface{}, opts *ListOptions) (int64, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	query := r.filter(r.client.Model(r.model), opts.Filters)
+	page, err := NewPaginator(query).Paginate(out, &PageOptions{
+		Page: opts.Page,
+		Size: opts.Size,
+		Sort: opts.Sort,
+	})
+	if err != nil {
+		if !IsValidationError(err) {
+			r.client.logger.Errorw("list records error", "method", "List", "error", err)
+		}
+		return 0, err
+	}
+	return page.Total, nil
+}
+
+// Update to update the selected fields of a record, all non-blank fields are updated if fields is empty
+// The fields can be either the struct field names or the column names.
+func (r *Repository) Update(value interface{}, fields ...string) error {
+	if err := validate(value); err != nil {
+		return err
+	}
+	scope := r.client.NewScope(value)
+	if scope.PrimaryKeyZero() {
+		return &ValidationError{Field: "id", Message: "is required"}
+	}
+
+	var attrs interface{} = value
+	if len(fields) > 0 {
+		m := make(map[string]interface{}, len(fields))
+		for _, name := range fields {
+			field, ok := scope.FieldByName(name)
+			if !ok {
+				return &ValidationError{Field: name, Message: "is not a field of the model"}
+			}
+			m[field.DBName] = field.Field.Interface()
+		}
+		attrs = m
+	}
+
+	if err := r.client.Model(value).Updates(attrs).Error; err != nil {
+		r.client.logger.Errorw("update record error", "method", "Update", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Delete to delete a record by its primary key
+func (r *Repository) Delete(value interface{}) error {
+	// gorm deletes all records when the primary key is blank
+	if r.client.NewScope(value).PrimaryKeyZero() {
+		return &ValidationError{Field: "id", Message: "is required"}
+	}
+	res := r.client.Delete(value)
+	if res.Error != nil {
+		r.client.logger.Errorw("delete record error", "method", "Delete", "error", res.Error)
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// filter to add the filters to the query in the order of the columns, so that the SQL is deterministic
+// gorm binds a slice in a map condition as one value, so the IN conditions are built here.
+func (r *Repository) filter(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
+	columns := make([]string, 0, len(filters))
+	for column := range filters {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	scope := r.client.NewScope(r.model)
+	for _, column := range columns {
+		value := filters[column]
+		switch {
+		case value == nil:
+			query = query.Where(scope.Quote(column) + " IS NULL")
+		case isList(value):
+			query = query.Where(scope.Quote(column)+" IN (?)", value)
+		default:
+			query = query.Where(scope.Quote(column)+" = ?", value)
+		}
+	}
+	return query
+}
+
+func (r *Repository) transaction(fn func(tx *gorm.DB) error) error {
+	tx := r.client.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// isList to check whether the value is a slice or an array, except []byte which is one value
+func isList(v interface{}) bool {
+	if _, ok := v.([]byte); ok {
+		return false
+	}
+	kind := reflect.ValueOf(v).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+func isBlank(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	return reflect.ValueOf(v).IsZero()
+}
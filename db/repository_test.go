@@ -0,0 +1,201 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	// use sqlite library
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+
+	"github.com/uhhc/sdk-common-go/log"
+)
+
+type testUser struct {
+	ID     uint
+	Name   string
+	Status int
+}
+
+func (u *testUser) Validate() error {
+	if u.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// newTestClient to get a client of an in-memory sqlite database with the users table
+func newTestClient(t *testing.T) *DbClient {
+	t.Helper()
+	gormDB, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection of :memory: is a new database
+	gormDB.DB().SetMaxOpenConns(1)
+	if err := gormDB.AutoMigrate(&testUser{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &DbClient{DB: gormDB, logger: *log.NewLogger(&log.Option{OutputPaths: []string{"stderr"}, LogLevel: "error"})}
+}
+
+func TestRepositoryCRUD(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+	repo := client.Repository(&testUser{})
+
+	user := &testUser{Name: "alice", Status: 1}
+	if err := repo.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(&testUser{}); !IsValidationError(err) {
+		t.Errorf("got error %v, want a validation error", err)
+	}
+
+	var got testUser
+	if err := repo.GetByID(user.ID, &got); err != nil || got.Name != "alice" {
+		t.Errorf("got %+v and error %v, want alice", got, err)
+	}
+	if err := repo.GetByID(0, &got); !IsValidationError(err) {
+		t.Errorf("got error %v for a blank id, want a validation error", err)
+	}
+	if err := repo.GetByID(100, &got); err != ErrNotFound || !IsNotFound(err) {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+
+	user.Name, user.Status = "bob", 2
+	if err := repo.Update(user, "Status"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.GetByID(user.ID, &got); err != nil || got.Name != "alice" || got.Status != 2 {
+		t.Errorf("got %+v and error %v, want only the status updated", got, err)
+	}
+	if err := repo.Update(user, "Unknown"); !IsValidationError(err) {
+		t.Errorf("got error %v for an unknown field, want a validation error", err)
+	}
+
+	if err := repo.Delete(&testUser{}); !IsValidationError(err) {
+		t.Errorf("got error %v for a blank primary key, want a validation error", err)
+	}
+	if err := repo.Delete(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(user); err != ErrNotFound {
+		t.Errorf("got error %v for a deleted record, want ErrNotFound", err)
+	}
+}
+
+func TestRepositoryBatchCreate(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+	repo := client.Repository(&testUser{})
+
+	users := []testUser{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	if err := repo.BatchCreate(users, 2); err != nil {
+		t.Fatal(err)
+	}
+	if users[2].ID == 0 {
+		t.Error("got a blank id, want the ids set on the slice")
+	}
+	if err := repo.BatchCreate([]testUser{{Name: "d"}, {}}, 2); !IsValidationError(err) {
+		t.Errorf("got error %v, want a validation error", err)
+	}
+	if err := repo.BatchCreate(testUser{Name: "e"}, 2); !IsValidationError(err) {
+		t.Errorf("got error %v for a struct, want a validation error", err)
+	}
+
+	var count int
+	client.Model(&testUser{}).Count(&count)
+	if count != 3 {
+		t.Errorf("got %d records, want 3 since the invalid batch is not written", count)
+	}
+}
+
+func TestRepositoryList(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+	repo := client.Repository(&testUser{})
+	for i, status := range []int{1, 2, 3, 1, 2} {
+		if err := repo.Create(&testUser{Name: string(rune('a' + i)), Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		opts      *ListOptions
+		wantTotal int64
+		wantNames string
+	}{
+		{"all", nil, 5, "abcde"},
+		{"in", &ListOptions{Filters: map[string]interface{}{"status": []int{1, 2}}, Sort: []string{"name desc"}}, 4, "edba"},
+		{"equal", &ListOptions{Filters: map[string]interface{}{"status": 3}}, 1, "c"},
+		{"and", &ListOptions{Filters: map[string]interface{}{"status": []int{1, 2}, "name": "d"}}, 1, "d"},
+		{"page", &ListOptions{Sort: []string{"id"}, Page: 2, Size: 2}, 5, "cd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []testUser
+			total, err := repo.List(&users, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names string
+			for _, u := range users {
+				names += u.Name
+			}
+			if total != tt.wantTotal || names != tt.wantNames {
+				t.Errorf("got total %d and %q, want %d and %q", total, names, tt.wantTotal, tt.wantNames)
+			}
+		})
+	}
+
+	var users []testUser
+	if _, err := repo.List(&users, &ListOptions{Sort: []string{"name; drop table test_users"}}); !IsValidationError(err) {
+		t.Errorf("got error %v for an invalid sort, want a validation error", err)
+	}
+}
+
+func TestRepositoryFilterSQL(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+	repo := client.Repository(&testUser{})
+
+	var (
+		sql  string
+		vars []interface{}
+	)
+	client.Callback().Query().After("gorm:query").Register("test:sql", func(scope *gorm.Scope) {
+		sql, vars = scope.SQL, scope.SQLVars
+	})
+	query := repo.filter(client.Model(&testUser{}), map[string]interface{}{
+		"status": []int{1, 2},
+		"name":   "a",
+		"id":     nil,
+	})
+	var users []testUser
+	if err := query.Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := `WHERE ("id" IS NULL) AND ("name" = ?) AND ("status" IN (?,?))`
+	if !strings.HasSuffix(sql, want) || len(vars) != 3 {
+		t.Errorf("got %s %v, want %s", sql, vars, want)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := error(&ValidationError{Field: "name", Message: "is required"})
+	if err.Error() != "validation error: name is required" {
+		t.Errorf("got %q", err.Error())
+	}
+	if err := (&ValidationError{Message: "bad"}); err.Error() != "validation error: bad" {
+		t.Errorf("got %q", err.Error())
+	}
+	if !IsValidationError(validate(&testUser{})) {
+		t.Error("got no validation error for an error of Validate")
+	}
+	if IsNotFound(errors.New("other")) || !IsNotFound(gorm.ErrRecordNotFound) || convertError(gorm.ErrRecordNotFound) != ErrNotFound {
+		t.Error("got wrong not found checks")
+	}
+}
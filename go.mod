@@ -3,10 +3,13 @@ module github.com/uhhc/sdk-common-go
 go 1.13
 
 require (
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/go-resty/resty/v2 v2.3.0
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/gofrs/uuid v3.2.0+incompatible
 	github.com/jinzhu/gorm v1.9.11
+	github.com/spf13/cast v1.3.0
+	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.5.0
 	github.com/tidwall/pretty v1.0.2 // indirect
 	github.com/xdg/scram v0.0.0-20180814205039-7eeb5667e42c // indirect
@@ -0,0 +1,38 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Pinger is a client which can check its connection, such as *db.DbClient and *mongodb.MongoClient
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingCheck to check a client by its Ping method
+// Example:
+//
+// 		registry.Register("mysql", health.PingCheck(dbClient))
+// 		registry.Register("mongodb", health.PingCheck(mongo))
+//
+func PingCheck(p Pinger) Checker {
+	return CheckerFunc(p.Ping)
+}
+
+// HTTPCheck to check an HTTP dependency by GET of the url, it is healthy if the status code is 2xx or 3xx
+// The client is usually got by httpclient.NewClient, so that the base url, headers and TLS settings apply.
+func HTTPCheck(client *resty.Client, url string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		resp, err := client.R().SetContext(ctx).Get(url)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode() >= 400 {
+			return fmt.Errorf("health: GET %s returns status %d", url, resp.StatusCode())
+		}
+		return nil
+	})
+}
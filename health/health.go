@@ -0,0 +1,290 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default settings of the registry
+const (
+	DefaultTimeout  = 5 * time.Second
+	DefaultCacheTTL = 5 * time.Second
+)
+
+// Status of the checks
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker checks a dependency, it is healthy if the error is nil
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc is an adapter to use a function as Checker
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Config is the config of the registry
+type Config struct {
+	// Timeout is the default timeout of the checks, DefaultTimeout if it is 0
+	Timeout time.Duration
+	// CacheTTL is how long the results are reused, DefaultCacheTTL if it is 0, negative to disable the cache
+	CacheTTL time.Duration
+}
+
+// CheckOption changes a check
+type CheckOption func(*check)
+
+// WithTimeout to set the timeout of the check
+func WithTimeout(timeout time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// Liveness to include the check in the liveness besides the readiness
+// The liveness should only check the process itself, since failed liveness probes restart the process.
+func Liveness() CheckOption {
+	return func(c *check) {
+		c.liveness = true
+	}
+}
+
+// NonCritical to report the failure of the check without making the overall status down
+func NonCritical() CheckOption {
+	return func(c *check) {
+		c.nonCritical = true
+	}
+}
+
+// Registry keeps the named checks, and runs them concurrently with timeouts
+// The results are cached, so that the frequent probes do not overload the dependencies.
+type Registry struct {
+	cfg    Config
+	mu     sync.RWMutex
+	checks map[string]*check
+}
+
+type check struct {
+	name        string
+	checker     Checker
+	timeout     time.Duration
+	liveness    bool
+	nonCritical bool
+
+	// mu makes the concurrent callers wait for one run
+	mu        sync.Mutex
+	result    Result
+	checkedAt time.Time
+}
+
+// Report is the result of the checks
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Result is the result of a check
+type Result struct {
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Duration    string    `json:"duration"`
+	CheckedAt   time.Time `json:"checkedAt"`
+	NonCritical bool      `json:"nonCritical,omitempty"`
+}
+
+// NewRegistry to get a registry of checks
+// Example:
+//
+// 		registry := health.NewRegistry()
+// 		registry.Register("mysql", health.PingCheck(dbClient))
+// 		registry.Register("mongodb", health.PingCheck(mongo), health.WithTimeout(2*time.Second))
+// 		registry.Register("payment", health.HTTPCheck(client, "https://pay.example.com/healthz"), health.NonCritical())
+//
+// 		http.Handle("/healthz", registry.LivenessHandler())
+// 		http.Handle("/readyz", registry.ReadinessHandler())
+//
+func NewRegistry(configs ...*Config) *Registry {
+	var cfg Config
+	if len(configs) > 0 && configs[0] != nil {
+		cfg = *configs[0]
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = DefaultCacheTTL
+	}
+	return &Registry{
+		cfg:    cfg,
+		checks: map[string]*check{},
+	}
+}
+
+// Register to add a check, the check of the same name is replaced
+// The checks are only in the readiness unless the option Liveness is given.
+func (r *Registry) Register(name string, checker Checker, opts ...CheckOption) {
+	c := &check{
+		name:    name,
+		checker: checker,
+		timeout: r.cfg.Timeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = c
+}
+
+// Unregister to remove a check
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.checks, name)
+}
+
+// Liveness to run the checks of the liveness, the status is up if there is no check
+func (r *Registry) Liveness(ctx context.Context) *Report {
+	return r.run(ctx, true)
+}
+
+// Readiness to run all checks
+func (r *Registry) Readiness(ctx context.Context) *Report {
+	return r.run(ctx, false)
+}
+
+// LivenessHandler to get the http handler of the liveness, the status code is 503 if it is down
+func (r *Registry) LivenessHandler() http.Handler {
+	return r.handler(true)
+}
+
+// ReadinessHandler to get the http handler of the readiness, the status code is 503 if it is down
+func (r *Registry) ReadinessHandler() http.Handler {
+	return r.handler(false)
+}
+
+func (r *Registry) handler(liveness bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		report := r.run(req.Context(), liveness)
+		status := http.StatusOK
+		if report.Status != StatusUp {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		if req.Method == http.MethodGet {
+			_ = json.NewEncoder(w).Encode(report)
+		}
+	})
+}
+
+func (r *Registry) run(ctx context.Context, liveness bool) *Report {
+	r.mu.RLock()
+	var checks []*check
+	for _, c := range r.checks {
+		if !liveness || c.liveness {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].name < checks[j].name
+	})
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, r.cfg.CacheTTL)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusUp}
+	if len(checks) > 0 {
+		report.Checks = make(map[string]Result, len(checks))
+	}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusUp && !c.nonCritical {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// run to get the cached result, or run the check if the result is expired
+func (c *check) run(ctx context.Context, ttl time.Duration) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ttl > 0 && !c.checkedAt.IsZero() && time.Since(c.checkedAt) < ttl {
+		return c.result
+	}
+
+	// The check is not canceled with the request, so that its result can be cached
+	checkCtx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	start := time.Now()
+	err := c.call(checkCtx, ctx)
+
+	c.checkedAt = time.Now()
+	c.result = Result{
+		Status:      StatusUp,
+		Duration:    c.checkedAt.Sub(start).String(),
+		CheckedAt:   c.checkedAt,
+		NonCritical: c.nonCritical,
+	}
+	if err != nil {
+		c.result.Status = StatusDown
+		c.result.Error = err.Error()
+	}
+	if ctx.Err() != nil {
+		// Do not cache the result of a canceled request
+		c.checkedAt = time.Time{}
+	}
+	return c.result
+}
+
+// call to run the checker, it returns at the timeout even if the checker ignores ctx
+func (c *check) call(ctx, reqCtx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("health: check panics: %v", p)
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("health: check timeout after %s", c.timeout)
+	case <-reqCtx.Done():
+		return reqCtx.Err()
+	}
+}
@@ -0,0 +1,191 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingChecker counts the checks and returns err
+type countingChecker struct {
+	calls int32
+	err   error
+}
+
+func (c *countingChecker) Check(ctx context.Context) error {
+	atomic.AddInt32(&c.calls, 1)
+	return c.err
+}
+
+func TestRegistryCache(t *testing.T) {
+	registry := NewRegistry(&Config{CacheTTL: 50 * time.Millisecond})
+	checker := &countingChecker{}
+	registry.Register("db", checker)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			registry.Readiness(context.Background())
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&checker.calls); n != 1 {
+		t.Errorf("got %d checks, want 1 for the concurrent probes", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	registry.Readiness(context.Background())
+	if n := atomic.LoadInt32(&checker.calls); n != 2 {
+		t.Errorf("got %d checks, want 2 after the ttl", n)
+	}
+
+	uncached := NewRegistry(&Config{CacheTTL: -1})
+	checker = &countingChecker{}
+	uncached.Register("db", checker)
+	uncached.Readiness(context.Background())
+	uncached.Readiness(context.Background())
+	if n := atomic.LoadInt32(&checker.calls); n != 2 {
+		t.Errorf("got %d checks, want 2 without the cache", n)
+	}
+}
+
+func TestRegistryTimeout(t *testing.T) {
+	registry := NewRegistry(&Config{Timeout: time.Hour})
+	block := make(chan struct{})
+	defer close(block)
+	// The checker ignores ctx
+	registry.Register("stuck", CheckerFunc(func(ctx context.Context) error {
+		<-block
+		return nil
+	}), WithTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	report := registry.Readiness(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("got the report after %s, want it at the timeout", elapsed)
+	}
+	result := report.Checks["stuck"]
+	if report.Status != StatusDown || result.Status != StatusDown || !strings.Contains(result.Error, "timeout after 20ms") {
+		t.Errorf("got report %+v, want the timeout", report)
+	}
+}
+
+func TestRegistryCanceledRequest(t *testing.T) {
+	registry := NewRegistry()
+	block := make(chan struct{})
+	checker := &countingChecker{}
+	registry.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		select {
+		case <-block:
+		case <-ctx.Done():
+		}
+		return checker.Check(ctx)
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if report := registry.Readiness(ctx); report.Checks["slow"].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("got report %+v, want the error of the request", report)
+	}
+	// The result of the canceled request is not cached
+	close(block)
+	if report := registry.Readiness(context.Background()); report.Status != StatusUp {
+		t.Errorf("got report %+v, want up", report)
+	}
+}
+
+func TestRegistryPanic(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("panics", CheckerFunc(func(ctx context.Context) error {
+		panic("boom")
+	}))
+	report := registry.Readiness(context.Background())
+	if report.Status != StatusDown || report.Checks["panics"].Error != "health: check panics: boom" {
+		t.Errorf("got report %+v, want the panic as the error", report)
+	}
+}
+
+func TestRegistryStatus(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("process", &countingChecker{}, Liveness())
+	registry.Register("db", &countingChecker{})
+	registry.Register("payment", &countingChecker{err: errors.New("unavailable")}, NonCritical())
+
+	report := registry.Readiness(context.Background())
+	if report.Status != StatusUp || len(report.Checks) != 3 {
+		t.Fatalf("got report %+v, want up with the failed non-critical check", report)
+	}
+	payment := report.Checks["payment"]
+	if payment.Status != StatusDown || payment.Error != "unavailable" || !payment.NonCritical {
+		t.Errorf("got result %+v of the non-critical check", payment)
+	}
+
+	// The liveness only has the liveness checks
+	report = registry.Liveness(context.Background())
+	if _, ok := report.Checks["process"]; report.Status != StatusUp || len(report.Checks) != 1 || !ok {
+		t.Errorf("got liveness %+v, want the process check only", report)
+	}
+
+	registry.Register("db", &countingChecker{err: errors.New("refused")})
+	if report := registry.Readiness(context.Background()); report.Status != StatusDown {
+		t.Errorf("got report %+v, want down with the failed critical check", report)
+	}
+	if report := registry.Liveness(context.Background()); report.Status != StatusUp {
+		t.Errorf("got liveness %+v, want up since db is not in the liveness", report)
+	}
+
+	registry.Unregister("db")
+	if report := registry.Readiness(context.Background()); report.Status != StatusUp {
+		t.Errorf("got report %+v, want up after db is unregistered", report)
+	}
+	if report := NewRegistry().Liveness(context.Background()); report.Status != StatusUp || report.Checks != nil {
+		t.Errorf("got report %+v of no check, want up", report)
+	}
+}
+
+func TestRegistryHandlers(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("process", &countingChecker{}, Liveness())
+	registry.Register("db", &countingChecker{err: errors.New("refused")})
+
+	tests := []struct {
+		handler    http.Handler
+		method     string
+		wantStatus int
+		wantReport string
+	}{
+		{registry.LivenessHandler(), http.MethodGet, http.StatusOK, StatusUp},
+		{registry.ReadinessHandler(), http.MethodGet, http.StatusServiceUnavailable, StatusDown},
+		{registry.ReadinessHandler(), http.MethodHead, http.StatusServiceUnavailable, ""},
+		{registry.ReadinessHandler(), http.MethodPost, http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/readyz", nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: got status %d, want %d", tt.method, rec.Code, tt.wantStatus)
+		}
+		if tt.wantReport == "" {
+			if rec.Body.Len() != 0 {
+				t.Errorf("%s: got body %q, want none", tt.method, rec.Body)
+			}
+			continue
+		}
+		var report Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("%s: decode %q error: %v", tt.method, rec.Body, err)
+		}
+		if report.Status != tt.wantReport || rec.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("%s: got report %+v and headers %v", tt.method, report, rec.Header())
+		}
+	}
+}
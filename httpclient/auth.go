@@ -0,0 +1,394 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// AuthProvider sets the credentials on the outgoing requests
+type AuthProvider interface {
+	Authenticate(req *http.Request) error
+}
+
+// Refresher is implemented by the auth providers whose credentials can be refreshed
+// Refresh is called with the sent request when the server responds 401, then the request is sent again once.
+// The credentials should not be refreshed again if they have changed since the request was sent,
+// so that the concurrent 401 responses cause only one refresh.
+type Refresher interface {
+	Refresh(ctx context.Context, failed *http.Request) error
+}
+
+// AuthMiddleware to get a middleware which authenticates every request with the provider
+func AuthMiddleware(provider AuthProvider) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			refresher, ok := provider.(Refresher)
+			if !ok {
+				resp, _, err := authRoundTrip(next, provider, req)
+				return resp, err
+			}
+
+			// Keep a copy of the body to send the request again after refreshing
+			body, replayable, err := snapshotBody(req)
+			if err != nil {
+				return nil, err
+			}
+			resp, sent, err := authRoundTrip(next, provider, req)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized || !replayable {
+				return resp, err
+			}
+			if err := refresher.Refresh(req.Context(), sent); err != nil {
+				return resp, nil
+			}
+			// Discard the 401 response and try again with the new credentials
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			if body != nil {
+				req = req.Clone(req.Context())
+				req.Body = ioutil.NopCloser(bytes.NewReader(body))
+				req.GetBody = func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader(body)), nil
+				}
+			}
+			resp, _, err = authRoundTrip(next, provider, req)
+			return resp, err
+		})
+	}
+}
+
+// snapshotBody reads a copy of the body by GetBody, the request can not be sent again without GetBody
+func snapshotBody(req *http.Request) ([]byte, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, true, nil
+	}
+	if req.GetBody == nil {
+		return nil, false, nil
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, false, err
+	}
+	if rc == nil {
+		return nil, false, nil
+	}
+	defer rc.Close()
+	body, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, false, err
+	}
+	return body, true, nil
+}
+
+// authRoundTrip to send an authenticated copy of the request, the copy is returned with the response
+func authRoundTrip(next http.RoundTripper, provider AuthProvider, req *http.Request) (*http.Response, *http.Request, error) {
+	// A RoundTripper must not modify the request
+	r := req.Clone(req.Context())
+	if err := provider.Authenticate(r); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, r, err
+	}
+	resp, err := next.RoundTrip(r)
+	return resp, r, err
+}
+
+// BearerToken authenticates with a static bearer token
+type BearerToken string
+
+// Authenticate implements AuthProvider
+func (t BearerToken) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+// BasicAuth authenticates with username and password
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authenticate implements AuthProvider
+func (a *BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// HMACSigner signs the requests with HMAC-SHA256
+// The signed string is joined by "\n" from the method, the request URI, the timestamp
+// and the hex encoded SHA256 of the body, such as:
+//
+// 		POST
+// 		/v1/orders?id=1
+// 		1600000000
+// 		e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
+//
+// Then the headers are set:
+//
+// 		X-Timestamp: 1600000000
+// 		Authorization: HMAC-SHA256 KeyId=<KeyID>, Signature=<hex signature>
+//
+type HMACSigner struct {
+	KeyID  string
+	Secret []byte
+}
+
+// Authenticate implements AuthProvider
+func (s *HMACSigner) Authenticate(req *http.Request) error {
+	var body []byte
+	if req.Body != nil && req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		body, err = ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	sum := sha256.Sum256(body)
+	signed := strings.Join([]string{req.Method, req.URL.RequestURI(), timestamp, hex.EncodeToString(sum[:])}, "\n")
+
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(signed))
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("Authorization", fmt.Sprintf("HMAC-SHA256 KeyId=%s, Signature=%s", s.KeyID, hex.EncodeToString(mac.Sum(nil))))
+	return nil
+}
+
+// DefaultTokenRefreshBefore is how long before the expiry the OAuth2 token is refreshed
+const DefaultTokenRefreshBefore = 30 * time.Second
+
+// defaultTokenTimeout is the timeout of fetching the OAuth2 token if HTTPClient has no timeout
+const defaultTokenTimeout = 10 * time.Second
+
+// OAuth2ClientCredentials authenticates with a token of OAuth2 client credentials grant
+// The token is cached and refreshed RefreshBefore it expires. The token is fetched without holding
+// the lock, and the concurrent requests wait for the same fetch. The fetch is not canceled with the
+// request which starts it, it is bounded by the timeout of HTTPClient instead, so that the other requests
+// still get the token, and every request stops waiting when its own context is done.
+type OAuth2ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// RefreshBefore is DefaultTokenRefreshBefore if it is 0
+	RefreshBefore time.Duration
+	// HTTPClient is used to request the token, a client with 10s timeout is used if it is nil
+	// The fetch times out in 10s if the client has no timeout.
+	HTTPClient *http.Client
+
+	mu        sync.Mutex
+	token     string
+	tokenType string
+	expiry    time.Time
+	inflight  *tokenCall
+}
+
+// tokenCall is a token fetch in flight, done is closed when it finishes
+type tokenCall struct {
+	done chan struct{}
+	err  error
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Authenticate implements AuthProvider
+func (o *OAuth2ClientCredentials) Authenticate(req *http.Request) error {
+	token, tokenType, err := o.getToken(req.Context(), false, "")
+	if err != nil {
+		return err
+	}
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	req.Header.Set("Authorization", tokenType+" "+token)
+	return nil
+}
+
+// Refresh implements Refresher, the token is not fetched if it has changed since the failed request
+func (o *OAuth2ClientCredentials) Refresh(ctx context.Context, failed *http.Request) error {
+	var stale string
+	if failed != nil {
+		if parts := strings.SplitN(failed.Header.Get("Authorization"), " ", 2); len(parts) == 2 {
+			stale = parts[1]
+		}
+	}
+	_, _, err := o.getToken(ctx, true, stale)
+	return err
+}
+
+// getToken to get the cached token, it is fetched if it is empty or expiring, or force is true and the
+// token is stale, which is the cached token if stale is empty
+func (o *OAuth2ClientCredentials) getToken(ctx context.Context, force bool, stale string) (string, string, error) {
+	o.mu.Lock()
+	fetch := o.token == "" || o.expiring() || (force && (stale == "" || stale == o.token))
+	if !fetch {
+		token, tokenType := o.token, o.tokenType
+		o.mu.Unlock()
+		return token, tokenType, nil
+	}
+
+	call := o.inflight
+	if call == nil {
+		call = &tokenCall{done: make(chan struct{})}
+		o.inflight = call
+		go o.fetchToken(call)
+	}
+	o.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if call.err != nil {
+		return "", "", call.err
+	}
+	return o.token, o.tokenType, nil
+}
+
+// fetchToken to fetch the token for the call and cache it, the fetch is detached from the contexts
+// of the requests waiting for it
+func (o *OAuth2ClientCredentials) fetchToken(call *tokenCall) {
+	timeout := defaultTokenTimeout
+	if o.HTTPClient != nil && o.HTTPClient.Timeout > 0 {
+		timeout = o.HTTPClient.Timeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	token, err := o.fetch(ctx)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err == nil {
+		o.token = token.AccessToken
+		o.tokenType = token.TokenType
+		o.expiry = time.Time{}
+		if token.ExpiresIn > 0 {
+			o.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+		}
+	}
+	call.err = err
+	o.inflight = nil
+	close(call.done)
+}
+
+func (o *OAuth2ClientCredentials) expiring() bool {
+	if o.expiry.IsZero() {
+		return false
+	}
+	refreshBefore := o.RefreshBefore
+	if refreshBefore == 0 {
+		refreshBefore = DefaultTokenRefreshBefore
+	}
+	return time.Now().Add(refreshBefore).After(o.expiry)
+}
+
+// fetch to request a new token from the token endpoint
+func (o *OAuth2ClientCredentials) fetch(ctx context.Context) (*tokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	req, err := http.NewRequest(http.MethodPost, o.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+
+	client := o.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: defaultTokenTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("httpclient: request oauth2 token error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("httpclient: read oauth2 token error: %v", err)
+	}
+	if err := json.Unmarshal(body, &token); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("httpclient: decode oauth2 token error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		msg := token.Error
+		if token.ErrorDescription != "" {
+			msg += ": " + token.ErrorDescription
+		}
+		if msg == "" {
+			msg = "empty access token"
+		}
+		return nil, fmt.Errorf("httpclient: oauth2 token status %d: %s", resp.StatusCode, msg)
+	}
+	return &token, nil
+}
+
+// ErrNoAuthProvider is returned when the auth type in config is unknown
+var ErrNoAuthProvider = errors.New("httpclient: unknown auth type")
+
+// loadAuthProvider gets the auth provider from viper by AUTH_TYPE, which can be bearer, basic, hmac or oauth2
+func loadAuthProvider(keys configKeys) (AuthProvider, error) {
+	switch authType := strings.ToLower(viper.GetString(keys.key("AUTH_TYPE"))); authType {
+	case "":
+		return nil, nil
+	case "bearer":
+		return BearerToken(viper.GetString(keys.key("AUTH_TOKEN"))), nil
+	case "basic":
+		return &BasicAuth{
+			Username: viper.GetString(keys.key("AUTH_USERNAME")),
+			Password: viper.GetString(keys.key("AUTH_PASSWORD")),
+		}, nil
+	case "hmac":
+		return &HMACSigner{
+			KeyID:  viper.GetString(keys.key("AUTH_KEY_ID")),
+			Secret: []byte(viper.GetString(keys.key("AUTH_SECRET"))),
+		}, nil
+	case "oauth2":
+		var scopes []string
+		if s := viper.GetString(keys.key("AUTH_SCOPES")); s != "" {
+			scopes = strings.Split(s, ",")
+		}
+		return &OAuth2ClientCredentials{
+			TokenURL:     viper.GetString(keys.key("AUTH_TOKEN_URL")),
+			ClientID:     viper.GetString(keys.key("AUTH_CLIENT_ID")),
+			ClientSecret: viper.GetString(keys.key("AUTH_CLIENT_SECRET")),
+			Scopes:       scopes,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrNoAuthProvider, authType)
+	}
+}
@@ -0,0 +1,246 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer issues the tokens "token-1", "token-2", ... and counts the requests
+type tokenServer struct {
+	*httptest.Server
+	fetches   int32
+	delay     time.Duration
+	expiresIn int64
+}
+
+func newTokenServer() *tokenServer {
+	ts := &tokenServer{expiresIn: 3600}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n := atomic.AddInt32(&ts.fetches, 1)
+		time.Sleep(ts.delay)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "bearer",
+			"expires_in":   atomic.LoadInt64(&ts.expiresIn),
+		})
+	}))
+	return ts
+}
+
+func (ts *tokenServer) provider() *OAuth2ClientCredentials {
+	return &OAuth2ClientCredentials{TokenURL: ts.URL, ClientID: "client", ClientSecret: "secret"}
+}
+
+// newAPIServer responds 401 unless the bearer token is accepted, the accepted token is returned in the body
+func newAPIServer(accept func(token string) bool) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !accept(token) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		_, _ = fmt.Fprintf(w, "%s %s", token, body)
+	}))
+	return srv
+}
+
+func TestOAuth2TokenIsCached(t *testing.T) {
+	ts := newTokenServer()
+	defer ts.Close()
+	api := newAPIServer(func(token string) bool { return token == "token-1" })
+	defer api.Close()
+	client, err := NewClientWithOptions(WithAuth(ts.provider()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.R().Get(api.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode() != http.StatusOK || !strings.HasPrefix(resp.String(), "token-1") {
+			t.Fatalf("got %d %q, want 200 with token-1", resp.StatusCode(), resp.String())
+		}
+	}
+	if n := atomic.LoadInt32(&ts.fetches); n != 1 {
+		t.Errorf("got %d token fetches, want 1", n)
+	}
+}
+
+func TestOAuth2TokenRefreshedBeforeExpiry(t *testing.T) {
+	ts := newTokenServer()
+	defer ts.Close()
+	atomic.StoreInt64(&ts.expiresIn, 10)
+	provider := ts.provider()
+	// The token expiring in 10s is always within RefreshBefore
+	provider.RefreshBefore = time.Minute
+
+	for i := 1; i <= 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		if err := provider.Authenticate(req); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := req.Header.Get("Authorization"), fmt.Sprintf("Bearer token-%d", i); got != want {
+			t.Errorf("got Authorization %q, want %q", got, want)
+		}
+	}
+}
+
+func TestOAuth2RefreshOn401(t *testing.T) {
+	ts := newTokenServer()
+	defer ts.Close()
+	// The first token is revoked
+	api := newAPIServer(func(token string) bool { return token == "token-2" })
+	defer api.Close()
+	client, err := NewClientWithOptions(WithAuth(ts.provider()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.R().SetBody("payload").Post(api.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusOK || resp.String() != "token-2 payload" {
+		t.Errorf("got %d %q, want the body sent again with token-2", resp.StatusCode(), resp.String())
+	}
+	if n := atomic.LoadInt32(&ts.fetches); n != 2 {
+		t.Errorf("got %d token fetches, want 2", n)
+	}
+}
+
+func TestOAuth2ConcurrentRequestsShareFetches(t *testing.T) {
+	ts := newTokenServer()
+	defer ts.Close()
+	ts.delay = 50 * time.Millisecond
+	api := newAPIServer(func(token string) bool { return token == "token-2" })
+	defer api.Close()
+	client, err := NewClientWithOptions(WithAuth(ts.provider()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.R().Get(api.URL)
+			if err != nil {
+				errs <- err.Error()
+				return
+			}
+			if resp.StatusCode() != http.StatusOK {
+				errs <- fmt.Sprintf("status %d", resp.StatusCode())
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	// One fetch for the first token, and one refresh for all 401 responses of it
+	if got := atomic.LoadInt32(&ts.fetches); got != 2 {
+		t.Errorf("got %d token fetches, want 2", got)
+	}
+}
+
+func TestOAuth2CanceledRequestDoesNotFailWaiters(t *testing.T) {
+	ts := newTokenServer()
+	defer ts.Close()
+	ts.delay = 100 * time.Millisecond
+	provider := ts.provider()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	first := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://api", nil)
+		first <- provider.Authenticate(req)
+	}()
+	for atomic.LoadInt32(&ts.fetches) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://api", nil)
+	if err := provider.Authenticate(req); err != nil {
+		t.Fatalf("got error %v, want the token fetched by the canceled request", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer token-1" {
+		t.Errorf("got Authorization %q, want token-1", got)
+	}
+	if err := <-first; err != context.DeadlineExceeded {
+		t.Errorf("got error %v of the canceled request, want its own context error", err)
+	}
+	if got := atomic.LoadInt32(&ts.fetches); got != 1 {
+		t.Errorf("got %d token fetches, want 1", got)
+	}
+}
+
+func TestOAuth2TokenError(t *testing.T) {
+	ts := newTokenServer()
+	defer ts.Close()
+	provider := ts.provider()
+	provider.ClientSecret = "wrong"
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	err := provider.Authenticate(req)
+	if err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("got error %v, want invalid_client", err)
+	}
+}
+
+func TestHMACSigner(t *testing.T) {
+	secret := []byte("secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		signed := strings.Join([]string{r.Method, r.URL.RequestURI(), r.Header.Get("X-Timestamp"), hex.EncodeToString(sum[:])}, "\n")
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		want := "HMAC-SHA256 KeyId=key, Signature=" + hex.EncodeToString(mac.Sum(nil))
+		if r.Header.Get("Authorization") != want {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClientWithOptions(WithAuth(&HMACSigner{KeyID: "key", Secret: secret}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.R().SetBody(`{"id":1}`).Post(srv.URL + "/v1/orders?id=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		t.Errorf("got status %d, want the signature accepted", resp.StatusCode())
+	}
+}
@@ -0,0 +1,145 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ErrCircuitOpen is returned when the circuit breaker of the host is open
+var ErrCircuitOpen = errors.New("httpclient: circuit breaker is open")
+
+// BreakerConfig is the config of the per-host circuit breaker
+type BreakerConfig struct {
+	// FailureThreshold is the amount of consecutive failures to open the circuit, 0 disables the breaker
+	FailureThreshold int `config:"threshold"`
+	// Cooldown is how long the circuit stays open before it becomes half-open
+	Cooldown time.Duration `config:"cooldown" default:"30"`
+}
+
+func loadBreakerConfig(keys configKeys) *BreakerConfig {
+	cooldown := viper.GetInt64(keys.key("BREAKER_COOLDOWN"))
+	if cooldown == 0 {
+		cooldown = 30
+	}
+	return &BreakerConfig{
+		FailureThreshold: viper.GetInt(keys.key("BREAKER_THRESHOLD")),
+		Cooldown:         time.Duration(cooldown) * time.Second,
+	}
+}
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+type hostBreaker struct {
+	state    breakerState
+	failures int
+	openedAt time.Time
+	// probing means a request is being sent in half-open state
+	probing bool
+}
+
+type breaker struct {
+	cfg   BreakerConfig
+	mu    sync.Mutex
+	hosts map[string]*hostBreaker
+}
+
+// BreakerMiddleware to get a middleware of per-host circuit breaker
+// A failure is a transport error or a 5xx response. When the circuit of a host is open,
+// requests fail fast with ErrCircuitOpen until Cooldown passes, then one probe request
+// is let through to decide whether to close the circuit.
+func BreakerMiddleware(cfg *BreakerConfig) Middleware {
+	b := &breaker{
+		cfg:   *cfg,
+		hosts: map[string]*hostBreaker{},
+	}
+	if b.cfg.Cooldown <= 0 {
+		b.cfg.Cooldown = 30 * time.Second
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			host := req.URL.Host
+			if !b.allow(host) {
+				return nil, ErrCircuitOpen
+			}
+			resp, err := next.RoundTrip(req)
+			if err != nil && req.Context().Err() != nil {
+				// Canceled by the caller, it says nothing about the host
+				b.release(host)
+				return resp, err
+			}
+			b.record(host, err == nil && resp.StatusCode < 500)
+			return resp, err
+		})
+	}
+}
+
+func (b *breaker) allow(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hb, ok := b.hosts[host]
+	if !ok {
+		return true
+	}
+	switch hb.state {
+	case stateOpen:
+		if time.Since(hb.openedAt) < b.cfg.Cooldown {
+			return false
+		}
+		hb.state = stateHalfOpen
+		hb.probing = true
+		return true
+	case stateHalfOpen:
+		if hb.probing {
+			return false
+		}
+		hb.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *breaker) release(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if hb, ok := b.hosts[host]; ok {
+		hb.probing = false
+	}
+}
+
+func (b *breaker) record(host string, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hb, ok := b.hosts[host]
+	if !ok {
+		if success {
+			return
+		}
+		hb = &hostBreaker{}
+		b.hosts[host] = hb
+	}
+	hb.probing = false
+	if success {
+		hb.state = stateClosed
+		hb.failures = 0
+		return
+	}
+	hb.failures++
+	if hb.state == stateHalfOpen || hb.failures >= b.cfg.FailureThreshold {
+		hb.state = stateOpen
+		hb.openedAt = time.Now()
+	}
+}
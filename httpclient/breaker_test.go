@@ -0,0 +1,164 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHost answers the requests with the status, or with the error if it is not nil
+type fakeHost struct {
+	mu     sync.Mutex
+	status int
+	err    error
+	calls  int
+	// block holds the requests until it is closed, if it is not nil
+	block chan struct{}
+}
+
+func (h *fakeHost) set(status int, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status, h.err = status, err
+}
+
+func (h *fakeHost) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.mu.Lock()
+	h.calls++
+	status, err, block := h.status, h.err, h.block
+	h.mu.Unlock()
+	if block != nil {
+		select {
+		case <-block:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: status, Body: http.NoBody, Request: req}, nil
+}
+
+func (h *fakeHost) callCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls
+}
+
+func sendTo(t *testing.T, rt http.RoundTripper, ctx context.Context, url string) error {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rt.RoundTrip(req)
+	return err
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	host := &fakeHost{status: http.StatusInternalServerError}
+	rt := BreakerMiddleware(&BreakerConfig{FailureThreshold: 3, Cooldown: time.Minute})(host)
+	ctx := context.Background()
+
+	// A success resets the consecutive failures
+	_ = sendTo(t, rt, ctx, "http://a")
+	_ = sendTo(t, rt, ctx, "http://a")
+	host.set(http.StatusOK, nil)
+	_ = sendTo(t, rt, ctx, "http://a")
+	host.set(http.StatusInternalServerError, nil)
+	_ = sendTo(t, rt, ctx, "http://a")
+	_ = sendTo(t, rt, ctx, "http://a")
+	if err := sendTo(t, rt, ctx, "http://a"); err != nil {
+		t.Fatalf("got error %v, want the circuit closed before 3 consecutive failures", err)
+	}
+	if err := sendTo(t, rt, ctx, "http://a"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got error %v, want ErrCircuitOpen", err)
+	}
+	if host.callCount() != 6 {
+		t.Errorf("got %d calls, want the open circuit to fail fast", host.callCount())
+	}
+
+	// The other hosts are not affected, and the transport errors are failures too
+	host.set(0, errors.New("connection refused"))
+	for i := 0; i < 3; i++ {
+		if err := sendTo(t, rt, ctx, "http://b"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("got ErrCircuitOpen for host b after %d failures", i)
+		}
+	}
+	if err := sendTo(t, rt, ctx, "http://b"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("got error %v, want ErrCircuitOpen after the transport errors", err)
+	}
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	tests := []struct {
+		name       string
+		probe      int
+		wantClosed bool
+	}{
+		{"probe succeeds", http.StatusOK, true},
+		{"probe fails", http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := &fakeHost{status: http.StatusInternalServerError}
+			rt := BreakerMiddleware(&BreakerConfig{FailureThreshold: 1, Cooldown: 20 * time.Millisecond})(host)
+			ctx := context.Background()
+			_ = sendTo(t, rt, ctx, "http://a")
+			if err := sendTo(t, rt, ctx, "http://a"); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("got error %v, want ErrCircuitOpen", err)
+			}
+
+			time.Sleep(30 * time.Millisecond)
+			host.set(tt.probe, nil)
+			if err := sendTo(t, rt, ctx, "http://a"); err != nil {
+				t.Fatalf("got error %v, want the probe sent after the cooldown", err)
+			}
+			err := sendTo(t, rt, ctx, "http://a")
+			if closed := !errors.Is(err, ErrCircuitOpen); closed != tt.wantClosed {
+				t.Errorf("got error %v after the probe, want closed %v", err, tt.wantClosed)
+			}
+		})
+	}
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	host := &fakeHost{status: http.StatusInternalServerError}
+	rt := BreakerMiddleware(&BreakerConfig{FailureThreshold: 1, Cooldown: 20 * time.Millisecond})(host)
+	_ = sendTo(t, rt, context.Background(), "http://a")
+	time.Sleep(30 * time.Millisecond)
+
+	host.mu.Lock()
+	host.status, host.block = http.StatusOK, make(chan struct{})
+	host.mu.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	probed := make(chan error, 1)
+	go func() {
+		probed <- sendTo(t, rt, ctx, "http://a")
+	}()
+	for host.callCount() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := sendTo(t, rt, context.Background(), "http://a"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("got error %v while probing, want ErrCircuitOpen", err)
+	}
+
+	// A canceled probe says nothing about the host, the next request is the probe
+	cancel()
+	if err := <-probed; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got probe error %v, want context.Canceled", err)
+	}
+	host.mu.Lock()
+	close(host.block)
+	host.mu.Unlock()
+	if err := sendTo(t, rt, context.Background(), "http://a"); err != nil {
+		t.Errorf("got error %v, want the next probe sent", err)
+	}
+	if err := sendTo(t, rt, context.Background(), "http://a"); err != nil {
+		t.Errorf("got error %v, want the circuit closed by the probe", err)
+	}
+}
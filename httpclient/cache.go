@@ -0,0 +1,343 @@
+package httpclient
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/uhhc/sdk-common-go/log"
+)
+
+// CachedResponse is a response kept in the cache storage
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Expires is when the response becomes stale and needs revalidation
+	Expires time.Time
+	// Vary keeps the request headers named by the Vary header of the response
+	Vary map[string]string
+}
+
+// CacheStorage stores the cached responses
+type CacheStorage interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+}
+
+// CacheConfig is the config of the response cache
+// The cache is shared by all requests of the client, so the requests with Authorization or Cookie headers
+// are not cached. With Config.Auth, every request carries Authorization, so the cache is never used and
+// NewClient warns about it; use a client without Auth for the public resources to cache.
+type CacheConfig struct {
+	// Storage is required
+	Storage CacheStorage
+	// Logger logs the hits and misses at debug level if it is not nil
+	Logger *log.Logger
+}
+
+func loadCacheConfig(keys configKeys) *CacheConfig {
+	size := viper.GetInt(keys.key("CACHE_SIZE"))
+	if size <= 0 {
+		return nil
+	}
+	return &CacheConfig{
+		Storage: NewLRUStorage(size),
+	}
+}
+
+// CacheMiddleware to get a middleware which caches the responses of GET requests
+// It honors Cache-Control and Expires of the responses, and revalidates the stale
+// responses by ETag/If-None-Match and Last-Modified/If-Modified-Since.
+// The cache is shared by all requests of the client, so the requests with Authorization or Cookie
+// are not cached, and neither are the responses with "Cache-Control: private" or Set-Cookie.
+// A cached response is used only if the request headers named by its Vary header are the same.
+func CacheMiddleware(cfg *CacheConfig) Middleware {
+	c := &cache{
+		storage: cfg.Storage,
+		logger:  cfg.Logger,
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return c.roundTrip(next, req)
+		})
+	}
+}
+
+type cache struct {
+	storage CacheStorage
+	logger  *log.Logger
+}
+
+func (c *cache) roundTrip(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	reqCC := parseCacheControl(req.Header.Get("Cache-Control"))
+	if req.Method != http.MethodGet || reqCC.has("no-store") || hasCredentials(req) {
+		return next.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	cached, ok := c.storage.Get(key)
+	if ok && !cached.matches(req) {
+		ok = false
+	}
+	if ok && time.Now().Before(cached.Expires) && !reqCC.has("no-cache") {
+		c.log(req, "http cache hit", key)
+		return cached.response(req), nil
+	}
+
+	outReq := req
+	if ok {
+		// Revalidate the stale response
+		etag := cached.Header.Get("ETag")
+		lastModified := cached.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			outReq = req.Clone(req.Context())
+			if etag != "" {
+				outReq.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				outReq.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	resp, err := next.RoundTrip(outReq)
+	if err != nil {
+		return resp, err
+	}
+	if ok && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		for _, h := range []string{"Cache-Control", "Expires", "ETag", "Last-Modified", "Date"} {
+			if v := resp.Header.Get(h); v != "" {
+				cached.Header.Set(h, v)
+			}
+		}
+		cached.Expires = expiresOf(cached.Header)
+		c.storage.Set(key, cached)
+		c.log(req, "http cache revalidated", key)
+		return cached.response(req), nil
+	}
+
+	c.log(req, "http cache miss", key)
+	if !cacheable(resp) {
+		if ok {
+			c.storage.Delete(key)
+		}
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	c.storage.Set(key, &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		Expires:    expiresOf(resp.Header),
+		Vary:       varyOf(req, resp.Header),
+	})
+	return resp, nil
+}
+
+func (c *cache) log(req *http.Request, msg, key string) {
+	if logger := log.ContextLogger(req.Context(), c.logger); logger != nil {
+		logger.Debugw(msg, "url", key)
+	}
+}
+
+func (r *CachedResponse) matches(req *http.Request) bool {
+	for name, value := range r.Vary {
+		if req.Header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *CachedResponse) response(req *http.Request) *http.Response {
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("X-Cache", "HIT")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+func cacheable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	cc := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if cc.has("no-store") || cc.has("private") || resp.Header.Get("Vary") == "*" ||
+		resp.Header.Get("Set-Cookie") != "" {
+		return false
+	}
+	// Stale responses are kept only if they can be revalidated
+	return time.Now().Before(expiresOf(resp.Header)) ||
+		resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// hasCredentials reports whether the response to the request may be specific to a user
+func hasCredentials(req *http.Request) bool {
+	return req.Header.Get("Authorization") != "" || req.Header.Get("Cookie") != ""
+}
+
+func expiresOf(header http.Header) time.Time {
+	now := time.Now()
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	if cc.has("no-cache") {
+		return now
+	}
+	if v, ok := cc["max-age"]; ok {
+		if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+			age, _ := strconv.ParseInt(header.Get("Age"), 10, 64)
+			return now.Add(time.Duration(seconds-age) * time.Second)
+		}
+	}
+	if v := header.Get("Expires"); v != "" {
+		if t, err := http.ParseTime(v); err == nil {
+			return t
+		}
+		// Invalid Expires such as "0" means already expired
+		return now
+	}
+	return now
+}
+
+func varyOf(req *http.Request, header http.Header) map[string]string {
+	v := header.Get("Vary")
+	if v == "" {
+		return nil
+	}
+	vary := map[string]string{}
+	for _, name := range strings.Split(v, ",") {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if name != "" {
+			vary[name] = req.Header.Get(name)
+		}
+	}
+	return vary
+}
+
+type cacheControl map[string]string
+
+func parseCacheControl(v string) cacheControl {
+	cc := cacheControl{}
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		name := strings.ToLower(strings.TrimSpace(kv[0]))
+		if len(kv) == 2 {
+			cc[name] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		} else {
+			cc[name] = ""
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// LRUStorage is an in-memory CacheStorage which evicts the least recently used responses
+type LRUStorage struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewLRUStorage to get an in-memory storage keeping at most capacity responses
+func NewLRUStorage(capacity int) *LRUStorage {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &LRUStorage{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// Get implements CacheStorage
+func (s *LRUStorage) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.ll.MoveToFront(e)
+	// Return a copy, so that the caller can update it safely
+	resp := *e.Value.(*lruEntry).resp
+	resp.Header = resp.Header.Clone()
+	return &resp, true
+}
+
+// Set implements CacheStorage
+func (s *LRUStorage) Set(key string, resp *CachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.items[key]; ok {
+		e.Value.(*lruEntry).resp = resp
+		s.ll.MoveToFront(e)
+		return
+	}
+	s.items[key] = s.ll.PushFront(&lruEntry{key: key, resp: resp})
+	for s.ll.Len() > s.capacity {
+		oldest := s.ll.Back()
+		s.ll.Remove(oldest)
+		delete(s.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Delete implements CacheStorage
+func (s *LRUStorage) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.items[key]; ok {
+		s.ll.Remove(e)
+		delete(s.items, key)
+	}
+}
+
+// Len to get the amount of the cached responses
+func (s *LRUStorage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
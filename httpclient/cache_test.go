@@ -0,0 +1,149 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/uhhc/sdk-common-go/log/logtest"
+)
+
+// newCachedServer counts the requests and responds with the header set by setHeader
+func newCachedServer(setHeader func(h http.Header)) (*httptest.Server, *int32) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		setHeader(w.Header())
+		_, _ = w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	return srv, &hits
+}
+
+func TestCacheHit(t *testing.T) {
+	srv, hits := newCachedServer(func(h http.Header) {})
+	defer srv.Close()
+	client, err := NewClientWithOptions(WithCache(NewLRUStorage(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.R().Get(srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestCacheBypassesCredentials(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+	}{
+		{"authorization", "Authorization", "Bearer token"},
+		{"cookie", "Cookie", "session=abc"},
+	}
+	for _, tt := range tests {
+		srv, hits := newCachedServer(func(h http.Header) {})
+		client, err := NewClientWithOptions(WithCache(NewLRUStorage(10)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := client.R().SetHeader(tt.header, tt.value).Get(srv.URL); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// The request without credentials must not get the response of the one with credentials
+		if _, err := client.R().Get(srv.URL); err != nil {
+			t.Fatal(err)
+		}
+		if n := atomic.LoadInt32(hits); n != 3 {
+			t.Errorf("%s: got %d requests, want 3", tt.name, n)
+		}
+		srv.Close()
+	}
+}
+
+func TestCacheBypassesAuthProvider(t *testing.T) {
+	srv, hits := newCachedServer(func(h http.Header) {})
+	defer srv.Close()
+	logger, logs := logtest.New(t)
+	client, err := NewClientWithOptions(WithCache(NewLRUStorage(10)), WithAuth(BearerToken("token")), WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs.AssertLogged(zap.WarnLevel, "the http cache is not used since the requests carry the credentials of Auth")
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.R().Get(srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(hits); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+}
+
+func TestCacheSkipsPrivateResponses(t *testing.T) {
+	tests := []struct {
+		name      string
+		setHeader func(h http.Header)
+	}{
+		{"private", func(h http.Header) { h.Set("Cache-Control", "private, max-age=60") }},
+		{"set-cookie", func(h http.Header) { h.Set("Set-Cookie", "session=abc") }},
+	}
+	for _, tt := range tests {
+		srv, hits := newCachedServer(tt.setHeader)
+		storage := NewLRUStorage(10)
+		client, err := NewClientWithOptions(WithCache(storage))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := client.R().Get(srv.URL); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n := atomic.LoadInt32(hits); n != 2 || storage.Len() != 0 {
+			t.Errorf("%s: got %d requests and %d cached, want 2 and 0", tt.name, n, storage.Len())
+		}
+		srv.Close()
+	}
+}
+
+func TestCacheVary(t *testing.T) {
+	srv, hits := newCachedServer(func(h http.Header) { h.Set("Vary", "Accept-Language") })
+	defer srv.Close()
+	client, err := NewClientWithOptions(WithCache(NewLRUStorage(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		lang string
+		hits int32
+	}{
+		{"en", 1},
+		{"en", 1},
+		{"zh", 2},
+	} {
+		resp, err := client.R().SetHeader("Accept-Language", tt.lang).Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.String() != tt.lang {
+			t.Errorf("got body %q, want the response for %q", resp.String(), tt.lang)
+		}
+		if n := atomic.LoadInt32(hits); n != tt.hits {
+			t.Errorf("Accept-Language %s: got %d requests, want %d", tt.lang, n, tt.hits)
+		}
+	}
+}
@@ -1,27 +1,352 @@
 package httpclient
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/spf13/viper"
+
+	"github.com/uhhc/sdk-common-go/config"
+	"github.com/uhhc/sdk-common-go/log"
 )
 
+const envPrefix = "HTTP_CLIENT_"
+
 func init() {
-	viper.AutomaticEnv()
+	config.GlobalEnv()
+}
+
+// Config is the config of http client
+// The config tags are the keys of the config package, such as "http_client.timeout" for the section "http_client".
+// RateLimit, Cache and Auth are read from viper or set by the options.
+type Config struct {
+	// Timeout is the request timeout, 10s by default
+	// The number in viper or the config package is in seconds, a string like "500ms" is parsed as a duration.
+	Timeout time.Duration `config:"timeout" default:"10"`
+	// DynamicTimeout overrides Timeout if it is not nil, it can be changed while the client is in use
+	DynamicTimeout *DynamicTimeout
+	// Retry is disabled if it is nil
+	Retry *RetryConfig `config:"retry"`
+	// Breaker is disabled if it is nil
+	Breaker *BreakerConfig `config:"breaker"`
+	// RateLimit is disabled if it is nil
+	RateLimit *RateLimitConfig
+	// Cache is disabled if it is nil, it is not used by the requests authenticated by Auth, see CacheConfig
+	Cache *CacheConfig
+	// Auth authenticates every request if it is not nil
+	Auth AuthProvider
+	// Logger logs every request if it is not nil, the logger in the context of a request takes precedence
+	Logger *log.Logger
+	// LogBodyLimit is the max size of the logged bodies in bytes at debug level
+	LogBodyLimit int `config:"log_body_limit"`
+
+	BaseURL   string            `config:"base_url"`
+	Headers   map[string]string `config:"headers"`
+	UserAgent string            `config:"user_agent"`
+
+	// TLS settings, the files are PEM encoded
+	CertFile           string `config:"cert_file"`
+	KeyFile            string `config:"key_file"`
+	Certificates       []tls.Certificate
+	CAFile             string `config:"ca_file"`
+	RootCAs            *x509.CertPool
+	InsecureSkipVerify bool `config:"insecure_skip_verify"`
+
+	// Proxy is the proxy URL, the proxy from environment (HTTP_PROXY/HTTPS_PROXY) is used if it is empty
+	Proxy string `config:"proxy"`
+
+	// Connection pool settings of the transport, 0 means using the default value
+	MaxIdleConns        int `config:"max_idle_conns"`
+	MaxIdleConnsPerHost int `config:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int `config:"max_conns_per_host"`
+	// IdleConnTimeout is parsed in the same way as Timeout
+	IdleConnTimeout time.Duration `config:"idle_conn_timeout"`
 }
 
 // NewClient to return a Resty http client
-func NewClient() *resty.Client {
+// If config is not given, the config will be read from viper.
+// The invalid settings, such as an invalid HTTP_CLIENT_TIMEOUT or TLS files which can not be loaded,
+// are logged and skipped, so that the defaults are used instead. Use NewClientE to get the error.
+func NewClient(configs ...*Config) *resty.Client {
+	var cfg *Config
+	if len(configs) > 0 && configs[0] != nil {
+		cfg = configs[0]
+	} else {
+		var err error
+		if cfg, err = loadConfig(""); err != nil {
+			logConfigError(cfg, err)
+		}
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		logConfigError(cfg, err)
+	}
+	return client
+}
+
+// NewClientE to return a Resty http client, the same as NewClient except that it returns the error
+// of the invalid config instead of skipping the invalid settings
+// Example:
+//
+// 		client, err := httpclient.NewClientE(&cfg.HTTP)
+// 		if err != nil {
+// 			return err
+// 		}
+//
+func NewClientE(configs ...*Config) (*resty.Client, error) {
+	if len(configs) > 0 && configs[0] != nil {
+		return checkClient(newClient(configs[0]))
+	}
+	cfg, err := loadConfig("")
+	if err != nil {
+		return nil, err
+	}
+	return checkClient(newClient(cfg))
+}
+
+// checkClient to drop the client built with an invalid config
+func checkClient(client *resty.Client, err error) (*resty.Client, error) {
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// warnConfig to report the config which works but is likely a mistake, by the logger of the config if any
+func warnConfig(cfg *Config, msg string) {
+	if cfg.Logger != nil {
+		cfg.Logger.Warnw(msg)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "httpclient: %s\n", msg)
+}
+
+// logConfigError to report the invalid config skipped by NewClient, by the logger of the config if any
+func logConfigError(cfg *Config, err error) {
+	if cfg != nil && cfg.Logger != nil {
+		cfg.Logger.Errorw("invalid http client config, the defaults are used", "error", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "httpclient: invalid config, the defaults are used: %v\n", err)
+}
+
+// newClient to build the client, the client is returned with the invalid settings skipped even if the
+// error is not nil
+func newClient(cfg *Config) (*resty.Client, error) {
 	// Create a Resty Client
 	client := resty.New()
 
-	// Set client timeout
-	timeout := viper.GetInt64("HTTP_CLIENT_TIMEOUT")
+	// Set client timeout, the dynamic timeout is applied by the outermost middleware
+	timeout := cfg.Timeout
 	if timeout == 0 {
-		timeout = 10
+		timeout = 10 * time.Second
+	}
+	if cfg.DynamicTimeout != nil {
+		client.SetTimeout(0)
+	} else {
+		client.SetTimeout(timeout)
 	}
-	client.SetTimeout(time.Duration(timeout) * time.Second)
 
-	return client
+	if cfg.BaseURL != "" {
+		client.SetHostURL(cfg.BaseURL)
+	}
+	client.SetHeaders(cfg.Headers)
+	if cfg.UserAgent != "" {
+		client.SetHeader("User-Agent", cfg.UserAgent)
+	}
+
+	// The transport must be set up before it is wrapped by the middlewares
+	err := setTransport(client, cfg)
+
+	// Set retry and circuit breaker
+	applyRetry(client, cfg.Retry)
+	var middlewares []Middleware
+	if cfg.DynamicTimeout != nil {
+		middlewares = append(middlewares, TimeoutMiddleware(cfg.DynamicTimeout))
+	}
+	// The requests may carry their own loggers even if the config has none, see log.WithContext
+	client.OnBeforeRequest(countAttempts)
+	middlewares = append(middlewares, LoggingMiddleware(cfg.Logger, cfg.LogBodyLimit))
+	// The cache is inside the auth, so that it sees the credentials set by the auth provider
+	if cfg.Auth != nil {
+		middlewares = append(middlewares, AuthMiddleware(cfg.Auth))
+	}
+	if cfg.Cache != nil && cfg.Cache.Storage != nil {
+		if cfg.Auth != nil {
+			warnConfig(cfg, "the http cache is not used since the requests carry the credentials of Auth")
+		}
+		cacheCfg := *cfg.Cache
+		if cacheCfg.Logger == nil {
+			cacheCfg.Logger = cfg.Logger
+		}
+		middlewares = append(middlewares, CacheMiddleware(&cacheCfg))
+	}
+	if cfg.RateLimit.enabled() {
+		middlewares = append(middlewares, RateLimitMiddleware(cfg.RateLimit))
+	}
+	if cfg.Breaker != nil && cfg.Breaker.FailureThreshold > 0 {
+		middlewares = append(middlewares, BreakerMiddleware(cfg.Breaker))
+	}
+	Use(client, middlewares...)
+
+	return client, err
+}
+
+func setTransport(client *resty.Client, cfg *Config) error {
+	transport, ok := client.GetClient().Transport.(*http.Transport)
+	if !ok {
+		return errors.New("httpclient: transport is not an *http.Transport")
+	}
+
+	if cfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return fmt.Errorf("httpclient: invalid proxy %q: %v", cfg.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	certs := cfg.Certificates
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return fmt.Errorf("httpclient: load client certificate error: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	rootCAs := cfg.RootCAs
+	if cfg.CAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("httpclient: read CA file error: %v", err)
+		}
+		if rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("httpclient: no certificate found in CA file %s", cfg.CAFile)
+		}
+	}
+	if len(certs) > 0 || rootCAs != nil || cfg.InsecureSkipVerify {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.Certificates = append(transport.TLSClientConfig.Certificates, certs...)
+		if rootCAs != nil {
+			transport.TLSClientConfig.RootCAs = rootCAs
+		}
+		transport.TLSClientConfig.InsecureSkipVerify = cfg.InsecureSkipVerify
+	}
+	return nil
+}
+
+// configKeys is the upper-case name of a client profile, whose viper keys are
+// prefixed with HTTP_CLIENT_<NAME>_ and fall back to the HTTP_CLIENT_ ones.
+type configKeys string
+
+func (name configKeys) key(k string) string {
+	if name != "" {
+		if named := envPrefix + string(name) + "_" + k; viper.IsSet(named) {
+			return named
+		}
+	}
+	return envPrefix + k
+}
+
+// loadConfig to read the config of the profile from viper
+// The config is returned with the invalid settings left as the defaults even if the error is not nil,
+// the error is the first invalid setting.
+func loadConfig(name string) (*Config, error) {
+	keys := configKeys(strings.ToUpper(name))
+	var firstErr error
+	check := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	auth, err := loadAuthProvider(keys)
+	check(err)
+	timeout, err := getDuration(keys.key("TIMEOUT"))
+	check(err)
+	idleConnTimeout, err := getDuration(keys.key("IDLE_CONN_TIMEOUT"))
+	check(err)
+	return &Config{
+		Timeout:             timeout,
+		Retry:               loadRetryConfig(keys),
+		Breaker:             loadBreakerConfig(keys),
+		RateLimit:           loadRateLimitConfig(keys),
+		Cache:               loadCacheConfig(keys),
+		Auth:                auth,
+		LogBodyLimit:        viper.GetInt(keys.key("LOG_BODY_LIMIT")),
+		BaseURL:             viper.GetString(keys.key("BASE_URL")),
+		Headers:             getStringMap(keys.key("HEADERS")),
+		UserAgent:           viper.GetString(keys.key("USER_AGENT")),
+		CertFile:            viper.GetString(keys.key("CERT_FILE")),
+		KeyFile:             viper.GetString(keys.key("KEY_FILE")),
+		CAFile:              viper.GetString(keys.key("CA_FILE")),
+		InsecureSkipVerify:  viper.GetBool(keys.key("INSECURE_SKIP_VERIFY")),
+		Proxy:               viper.GetString(keys.key("PROXY")),
+		MaxIdleConns:        viper.GetInt(keys.key("MAX_IDLE_CONNS")),
+		MaxIdleConnsPerHost: viper.GetInt(keys.key("MAX_IDLE_CONNS_PER_HOST")),
+		MaxConnsPerHost:     viper.GetInt(keys.key("MAX_CONNS_PER_HOST")),
+		IdleConnTimeout:     idleConnTimeout,
+	}, firstErr
+}
+
+// getDuration gets a duration from a number in seconds, or from a string like "1m30s"
+func getDuration(key string) (time.Duration, error) {
+	s := strings.TrimSpace(viper.GetString(key))
+	if s == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("httpclient: invalid duration %s=%q", key, s)
+	}
+	return d, nil
+}
+
+// getStringMap gets a map from a config file, or from a string like "k1=v1,k2=v2" in env
+func getStringMap(key string) map[string]string {
+	if m := viper.GetStringMapString(key); len(m) > 0 {
+		return m
+	}
+	s := viper.GetString(key)
+	if s == "" {
+		return nil
+	}
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 2 {
+			m[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return m
 }
@@ -0,0 +1,97 @@
+package httpclient
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/spf13/viper"
+)
+
+func TestSubSecondTimeouts(t *testing.T) {
+	client, err := NewClientWithOptions(
+		WithTimeout(1500*time.Millisecond),
+		WithConnectionPool(0, 0, 0, 500*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := client.GetClient().Timeout; got != 1500*time.Millisecond {
+		t.Errorf("got timeout %s, want 1.5s", got)
+	}
+	// The transport of the client is wrapped by the middlewares, so it is set up on a new one
+	cfg := &Config{}
+	WithConnectionPool(0, 0, 0, 500*time.Millisecond)(cfg)
+	base := resty.New()
+	if err := setTransport(base, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := base.GetClient().Transport.(*http.Transport).IdleConnTimeout; got != 500*time.Millisecond {
+		t.Errorf("got idle connection timeout %s, want 500ms", got)
+	}
+}
+
+func TestTimeoutFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 10 * time.Second},
+		{"3", 3 * time.Second},
+		{"0.5", 500 * time.Millisecond},
+		{"250ms", 250 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		_ = os.Setenv("HTTP_CLIENT_TIMEOUT", tt.value)
+		client, err := NewClientE()
+		if err != nil {
+			t.Fatalf("HTTP_CLIENT_TIMEOUT=%q: %v", tt.value, err)
+		}
+		if got := client.GetClient().Timeout; got != tt.want {
+			t.Errorf("HTTP_CLIENT_TIMEOUT=%q: got timeout %s, want %s", tt.value, got, tt.want)
+		}
+	}
+	_ = os.Unsetenv("HTTP_CLIENT_TIMEOUT")
+}
+
+func TestNewClientEReturnsError(t *testing.T) {
+	_ = os.Setenv("HTTP_CLIENT_TIMEOUT", "soon")
+	defer os.Unsetenv("HTTP_CLIENT_TIMEOUT")
+	if _, err := NewClientE(); err == nil || !strings.Contains(err.Error(), "HTTP_CLIENT_TIMEOUT") {
+		t.Errorf("got error %v, want the invalid timeout", err)
+	}
+
+	viper.Set("HTTP_CLIENT_PAYMENT_AUTH_TYPE", "unknown")
+	defer viper.Set("HTTP_CLIENT_PAYMENT_AUTH_TYPE", "")
+	if _, err := NewNamedClient("payment"); err == nil {
+		t.Error("got no error, want the invalid auth type of the profile")
+	}
+
+	if _, err := NewClientE(&Config{CAFile: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("got no error, want the CA file error")
+	}
+}
+
+func TestNewClientSkipsInvalidConfig(t *testing.T) {
+	_ = os.Setenv("HTTP_CLIENT_TIMEOUT", "soon")
+	_ = os.Setenv("HTTP_CLIENT_IDLE_CONN_TIMEOUT", "3")
+	defer os.Unsetenv("HTTP_CLIENT_TIMEOUT")
+	defer os.Unsetenv("HTTP_CLIENT_IDLE_CONN_TIMEOUT")
+
+	client := NewClient()
+	if got := client.GetClient().Timeout; got != 10*time.Second {
+		t.Errorf("got timeout %s, want the default 10s", got)
+	}
+	cfg, err := loadConfig("")
+	if err == nil || cfg.IdleConnTimeout != 3*time.Second {
+		t.Errorf("got idle connection timeout %s and error %v, want the valid setting 3s", cfg.IdleConnTimeout, err)
+	}
+
+	client = NewClient(&Config{Timeout: time.Second, CAFile: "/nonexistent/ca.pem"})
+	if got := client.GetClient().Timeout; got != time.Second {
+		t.Errorf("got timeout %s, want 1s", got)
+	}
+}
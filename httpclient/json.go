@@ -0,0 +1,189 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ErrorEnvelope is the decoded error body of an API, it supports the common formats:
+//
+// 		{"code": 1001, "message": "invalid id"}
+// 		{"error": "invalid_request", "error_description": "invalid id"}
+// 		{"error": {"code": "INVALID_ID", "message": "invalid id"}}
+//
+type ErrorEnvelope struct {
+	Code    string
+	Message string
+	Details interface{}
+}
+
+// APIError is returned by the JSON helpers when the status code is not 2xx
+// Example:
+//
+// 		var apiErr *httpclient.APIError
+// 		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+// 			// ...
+// 		}
+//
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Envelope is nil if the body can not be decoded
+	Envelope *ErrorEnvelope
+}
+
+// Error implements error interface
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("httpclient: %s %s: status %d", e.Method, e.URL, e.StatusCode)
+	if e.Envelope != nil {
+		if e.Envelope.Code != "" {
+			msg += ": " + e.Envelope.Code
+		}
+		if e.Envelope.Message != "" {
+			msg += ": " + e.Envelope.Message
+		}
+	}
+	return msg
+}
+
+// GetJSON to send a GET request and decode the JSON response into out
+func GetJSON(ctx context.Context, client *resty.Client, path string, query map[string]string, out interface{}) error {
+	req := newJSONRequest(ctx, client).SetQueryParams(query)
+	return doJSON(req, http.MethodGet, path, out)
+}
+
+// PostJSON to send a POST request with a JSON body and decode the JSON response into out
+// Example:
+//
+// 		var order Order
+// 		err := httpclient.PostJSON(ctx, client, "/v1/orders", &CreateOrder{Amount: 1}, &order)
+//
+func PostJSON(ctx context.Context, client *resty.Client, path string, body interface{}, out interface{}) error {
+	return doJSON(newJSONRequest(ctx, client).SetBody(body), http.MethodPost, path, out)
+}
+
+// PutJSON to send a PUT request with a JSON body and decode the JSON response into out
+func PutJSON(ctx context.Context, client *resty.Client, path string, body interface{}, out interface{}) error {
+	return doJSON(newJSONRequest(ctx, client).SetBody(body), http.MethodPut, path, out)
+}
+
+// PatchJSON to send a PATCH request with a JSON body and decode the JSON response into out
+func PatchJSON(ctx context.Context, client *resty.Client, path string, body interface{}, out interface{}) error {
+	return doJSON(newJSONRequest(ctx, client).SetBody(body), http.MethodPatch, path, out)
+}
+
+// DeleteJSON to send a DELETE request and decode the JSON response into out, out can be nil
+func DeleteJSON(ctx context.Context, client *resty.Client, path string, out interface{}) error {
+	return doJSON(newJSONRequest(ctx, client), http.MethodDelete, path, out)
+}
+
+func newJSONRequest(ctx context.Context, client *resty.Client) *resty.Request {
+	req := client.R().SetHeader("Accept", "application/json")
+	if ctx != nil {
+		req.SetContext(ctx)
+	}
+	return req
+}
+
+func doJSON(req *resty.Request, method, path string, out interface{}) error {
+	if req.Body != nil {
+		req.SetHeader("Content-Type", "application/json")
+	}
+	resp, err := req.Execute(method, path)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
+		return newAPIError(resp)
+	}
+	body := resp.Body()
+	if out == nil || len(body) == 0 || resp.StatusCode() == http.StatusNoContent {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("httpclient: decode response of %s %s error: %w", method, resp.Request.URL, err)
+	}
+	return nil
+}
+
+func newAPIError(resp *resty.Response) *APIError {
+	return &APIError{
+		Method:     resp.Request.Method,
+		URL:        resp.Request.URL,
+		StatusCode: resp.StatusCode(),
+		Header:     resp.Header(),
+		Body:       resp.Body(),
+		Envelope:   decodeErrorEnvelope(resp.Body()),
+	}
+}
+
+func decodeErrorEnvelope(body []byte) *ErrorEnvelope {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil
+	}
+
+	// {"error": {"code": ..., "message": ...}}
+	if nested, ok := raw["error"]; ok {
+		var inner map[string]json.RawMessage
+		if err := json.Unmarshal(nested, &inner); err == nil {
+			raw = inner
+		}
+	}
+
+	env := &ErrorEnvelope{
+		Code:    rawString(raw["code"]),
+		Message: rawString(raw["message"]),
+	}
+	if env.Code == "" {
+		env.Code = rawString(raw["error"])
+	}
+	if env.Message == "" {
+		env.Message = firstRawString(raw, "msg", "error_description", "detail", "title")
+	}
+	for _, key := range []string{"details", "errors", "data"} {
+		if v, ok := raw[key]; ok {
+			var details interface{}
+			if json.Unmarshal(v, &details) == nil {
+				env.Details = details
+				break
+			}
+		}
+	}
+	if env.Code == "" && env.Message == "" && env.Details == nil {
+		return nil
+	}
+	return env
+}
+
+func firstRawString(raw map[string]json.RawMessage, keys ...string) string {
+	for _, key := range keys {
+		if s := rawString(raw[key]); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// rawString converts a JSON string or number to string
+func rawString(v json.RawMessage) string {
+	if len(v) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(v, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(v, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type echoed struct {
+	Method      string            `json:"method"`
+	Query       string            `json:"query"`
+	ContentType string            `json:"contentType"`
+	Body        map[string]string `json:"body"`
+}
+
+func newJSONServer() (*httptest.Server, func(path string) string) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			e := echoed{Method: r.Method, Query: r.URL.RawQuery, ContentType: r.Header.Get("Content-Type")}
+			b, _ := ioutil.ReadAll(r.Body)
+			if len(b) > 0 {
+				_ = json.Unmarshal(b, &e.Body)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(e)
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/invalid":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code": 1001, "message": "invalid id", "details": {"field": "id"}}`))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/html":
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("<html>bad gateway</html>"))
+		case "/broken":
+			_, _ = w.Write([]byte("{broken"))
+		}
+	}))
+	return srv, func(path string) string { return srv.URL + path }
+}
+
+func TestJSONHelpers(t *testing.T) {
+	srv, _ := newJSONServer()
+	defer srv.Close()
+	client, err := NewClientE(&Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	body := map[string]string{"name": "demo"}
+
+	var got echoed
+	if err := GetJSON(ctx, client, "/echo", map[string]string{"id": "1"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != http.MethodGet || got.Query != "id=1" || got.ContentType != "" {
+		t.Errorf("got %+v, want GET with the query and without Content-Type", got)
+	}
+
+	send := map[string]func(out interface{}) error{
+		http.MethodPost:  func(out interface{}) error { return PostJSON(ctx, client, "/echo", body, out) },
+		http.MethodPut:   func(out interface{}) error { return PutJSON(ctx, client, "/echo", body, out) },
+		http.MethodPatch: func(out interface{}) error { return PatchJSON(ctx, client, "/echo", body, out) },
+	}
+	for method, fn := range send {
+		got = echoed{}
+		if err := fn(&got); err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		want := echoed{Method: method, ContentType: "application/json", Body: body}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", method, got, want)
+		}
+	}
+
+	got = echoed{}
+	if err := DeleteJSON(ctx, client, "/echo", &got); err != nil || got.Method != http.MethodDelete {
+		t.Errorf("got %+v and error %v, want DELETE", got, err)
+	}
+	if err := DeleteJSON(ctx, client, "/echo", nil); err != nil {
+		t.Errorf("got error %v, want nil without out", err)
+	}
+	if err := GetJSON(ctx, client, "/empty", nil, &got); err != nil {
+		t.Errorf("got error %v, want nil for the empty body", err)
+	}
+	if err := GetJSON(ctx, client, "/broken", nil, &got); err == nil || !strings.Contains(err.Error(), "decode response") {
+		t.Errorf("got error %v, want the decode error", err)
+	}
+}
+
+func TestJSONHelpersAPIError(t *testing.T) {
+	srv, url := newJSONServer()
+	defer srv.Close()
+	client, err := NewClientE(&Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		status   int
+		body     string
+		envelope *ErrorEnvelope
+		message  string
+	}{
+		{
+			path:     "/invalid",
+			status:   http.StatusBadRequest,
+			body:     `{"code": 1001, "message": "invalid id", "details": {"field": "id"}}`,
+			envelope: &ErrorEnvelope{Code: "1001", Message: "invalid id", Details: map[string]interface{}{"field": "id"}},
+			message:  "httpclient: POST " + url("/invalid") + ": status 400: 1001: invalid id",
+		},
+		{
+			path:    "/missing",
+			status:  http.StatusNotFound,
+			message: "httpclient: POST " + url("/missing") + ": status 404",
+		},
+		{
+			path:    "/html",
+			status:  http.StatusBadGateway,
+			body:    "<html>bad gateway</html>",
+			message: "httpclient: POST " + url("/html") + ": status 502",
+		},
+	}
+	for _, tt := range tests {
+		var out echoed
+		err := PostJSON(context.Background(), client, tt.path, map[string]string{}, &out)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("%s: got error %v, want APIError", tt.path, err)
+		}
+		if apiErr.Method != http.MethodPost || apiErr.StatusCode != tt.status || string(apiErr.Body) != tt.body {
+			t.Errorf("%s: got %+v", tt.path, apiErr)
+		}
+		if !reflect.DeepEqual(apiErr.Envelope, tt.envelope) {
+			t.Errorf("%s: got envelope %+v, want %+v", tt.path, apiErr.Envelope, tt.envelope)
+		}
+		if apiErr.Error() != tt.message {
+			t.Errorf("%s: got message %q, want %q", tt.path, apiErr.Error(), tt.message)
+		}
+	}
+}
+
+func TestDecodeErrorEnvelope(t *testing.T) {
+	tests := []struct {
+		body string
+		want *ErrorEnvelope
+	}{
+		{`{"code": 1001, "message": "invalid id"}`, &ErrorEnvelope{Code: "1001", Message: "invalid id"}},
+		{`{"error": "invalid_request", "error_description": "invalid id"}`, &ErrorEnvelope{Code: "invalid_request", Message: "invalid id"}},
+		{`{"error": {"code": "INVALID_ID", "message": "invalid id"}}`, &ErrorEnvelope{Code: "INVALID_ID", Message: "invalid id"}},
+		{`{"msg": "invalid id", "errors": ["id"]}`, &ErrorEnvelope{Message: "invalid id", Details: []interface{}{"id"}}},
+		{`{"title": "Not Found", "detail": "no order"}`, &ErrorEnvelope{Message: "no order"}},
+		{`{"status": "failed"}`, nil},
+		{`[1, 2]`, nil},
+		{`not json`, nil},
+		{``, nil},
+	}
+	for _, tt := range tests {
+		if got := decodeErrorEnvelope([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/uhhc/sdk-common-go/log"
+)
+
+// DefaultLogBodyLimit is the default max size of the logged bodies in bytes
+const DefaultLogBodyLimit = 1024
+
+const redacted = "REDACTED"
+
+var (
+	// redactedQueryKeys are the query parameters whose values will not be logged
+	redactedQueryKeys = []string{"token", "access_token", "refresh_token", "password", "secret", "key", "api_key", "apikey", "signature", "sig"}
+	// redactedHeaders are the headers whose values will not be logged
+	redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+)
+
+type attemptKey struct{}
+
+// countAttempts counts the attempts of a request in its context, so that the transport knows it
+func countAttempts(_ *resty.Client, r *resty.Request) error {
+	counter, ok := r.Context().Value(attemptKey{}).(*int32)
+	if !ok {
+		counter = new(int32)
+		r.SetContext(context.WithValue(r.Context(), attemptKey{}, counter))
+	}
+	atomic.AddInt32(counter, 1)
+	return nil
+}
+
+func attemptOf(ctx context.Context) int32 {
+	if counter, ok := ctx.Value(attemptKey{}).(*int32); ok {
+		return atomic.LoadInt32(counter)
+	}
+	return 1
+}
+
+// LoggingMiddleware to get a middleware which logs one line for every request
+// The bodies and headers are logged at debug level, at most bodyLimit bytes of each body.
+// The logger and request-scoped fields in the context of the request take precedence, see log.WithContext.
+// The logger can be nil, then only the requests whose contexts carry loggers are logged.
+func LoggingMiddleware(logger *log.Logger, bodyLimit int) Middleware {
+	if bodyLimit <= 0 {
+		bodyLimit = DefaultLogBodyLimit
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctxLogger := log.ContextLogger(req.Context(), logger)
+			if ctxLogger == nil || ctxLogger.SugaredLogger == nil {
+				return next.RoundTrip(req)
+			}
+			l := &requestLog{
+				logger:    ctxLogger,
+				req:       req,
+				start:     time.Now(),
+				debug:     ctxLogger.Desugar().Core().Enabled(zapcore.DebugLevel),
+				bodyLimit: bodyLimit,
+			}
+			if l.debug {
+				l.reqBody = peekRequestBody(req, bodyLimit)
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				l.finish(nil, 0, err)
+				return resp, err
+			}
+			resp.Body = &loggedBody{ReadCloser: resp.Body, log: l, resp: resp}
+			return resp, nil
+		})
+	}
+}
+
+type requestLog struct {
+	logger    *log.Logger
+	req       *http.Request
+	start     time.Time
+	debug     bool
+	bodyLimit int
+	reqBody   string
+	once      sync.Once
+}
+
+func (l *requestLog) finish(resp *http.Response, size int64, err error) {
+	l.once.Do(func() {
+		kv := []interface{}{
+			"method", l.req.Method,
+			"url", redactURL(l.req.URL),
+			"durationMs", float64(time.Since(l.start)) / float64(time.Millisecond),
+			"requestSize", l.req.ContentLength,
+			"attempt", attemptOf(l.req.Context()),
+		}
+		switch {
+		case err != nil:
+			l.logger.Errorw("http request error", append(kv, "error", err)...)
+		case resp.StatusCode >= 400:
+			l.logger.Warnw("http request", append(kv, "status", resp.StatusCode, "responseSize", size)...)
+		default:
+			l.logger.Infow("http request", append(kv, "status", resp.StatusCode, "responseSize", size)...)
+		}
+	})
+}
+
+type loggedBody struct {
+	io.ReadCloser
+	log  *requestLog
+	resp *http.Response
+	size int64
+	buf  bytes.Buffer
+	once sync.Once
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if b.log.debug && b.buf.Len() < b.log.bodyLimit {
+		rest := b.log.bodyLimit - b.buf.Len()
+		if rest > n {
+			rest = n
+		}
+		b.buf.Write(p[:rest])
+	}
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+func (b *loggedBody) done() {
+	b.once.Do(func() {
+		b.log.finish(b.resp, b.size, nil)
+		if !b.log.debug {
+			return
+		}
+		b.log.logger.Debugw(
+			"http request detail",
+			"method", b.log.req.Method,
+			"url", redactURL(b.log.req.URL),
+			"requestHeaders", redactHeaders(b.log.req.Header),
+			"requestBody", b.log.reqBody,
+			"responseHeaders", redactHeaders(b.resp.Header),
+			"responseBody", b.buf.String(),
+		)
+	})
+}
+
+func peekRequestBody(req *http.Request, limit int) string {
+	if req.Body == nil || req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	b, _ := ioutil.ReadAll(io.LimitReader(body, int64(limit)))
+	return string(b)
+}
+
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	c := *u
+	if c.User != nil {
+		c.User = url.User(c.User.Username())
+	}
+	query := c.Query()
+	changed := false
+	for key := range query {
+		for _, k := range redactedQueryKeys {
+			if strings.EqualFold(key, k) {
+				query.Set(key, redacted)
+				changed = true
+			}
+		}
+	}
+	if changed {
+		c.RawQuery = query.Encode()
+	}
+	return c.String()
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	m := make(map[string]string, len(header))
+	for k, v := range header {
+		m[k] = strings.Join(v, ", ")
+	}
+	for _, k := range redactedHeaders {
+		if _, ok := m[k]; ok {
+			m[k] = redacted
+		}
+	}
+	return m
+}
@@ -0,0 +1,203 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/uhhc/sdk-common-go/log"
+)
+
+// Option changes the config of the http client
+type Option func(*Config)
+
+// NewClientWithOptions to return a Resty http client with the options
+// The options are applied on top of the config read from viper.
+// Example:
+//
+// 		client, err := httpclient.NewClientWithOptions(
+// 			httpclient.WithBaseURL("https://api.example.com"),
+// 			httpclient.WithHeader("X-App", "demo"),
+// 			httpclient.WithTimeout(5*time.Second),
+// 		)
+//
+func NewClientWithOptions(opts ...Option) (*resty.Client, error) {
+	return NewNamedClient("", opts...)
+}
+
+// NewNamedClient to return a Resty http client of a named profile
+// The profile is read from viper keys like HTTP_CLIENT_<NAME>_BASE_URL, which fall back to HTTP_CLIENT_BASE_URL.
+// Example:
+//
+// 		// HTTP_CLIENT_PAYMENT_BASE_URL=https://pay.example.com
+// 		// HTTP_CLIENT_PAYMENT_TIMEOUT=3
+// 		client, err := httpclient.NewNamedClient("payment")
+//
+func NewNamedClient(name string, opts ...Option) (*resty.Client, error) {
+	cfg, err := loadConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return checkClient(newClient(cfg))
+}
+
+// WithConfig to replace the whole config, the options after it still apply
+func WithConfig(config Config) Option {
+	return func(c *Config) {
+		*c = config
+	}
+}
+
+// WithTimeout to set the request timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.Timeout = timeout
+	}
+}
+
+// WithDynamicTimeout to set a request timeout which can be changed while the client is in use
+// Example:
+//
+// 		timeout := httpclient.NewDynamicTimeout(10 * time.Second)
+// 		client, err := httpclient.NewClientWithOptions(httpclient.WithDynamicTimeout(timeout))
+// 		// Later, such as when the config is reloaded
+// 		timeout.Set(5 * time.Second)
+//
+func WithDynamicTimeout(timeout *DynamicTimeout) Option {
+	return func(c *Config) {
+		c.DynamicTimeout = timeout
+	}
+}
+
+// WithBaseURL to set the base URL of relative request URLs
+func WithBaseURL(baseURL string) Option {
+	return func(c *Config) {
+		c.BaseURL = baseURL
+	}
+}
+
+// WithHeader to set a default header of every request
+func WithHeader(key, value string) Option {
+	return func(c *Config) {
+		if c.Headers == nil {
+			c.Headers = map[string]string{}
+		}
+		c.Headers[key] = value
+	}
+}
+
+// WithHeaders to set default headers of every request
+func WithHeaders(headers map[string]string) Option {
+	return func(c *Config) {
+		for k, v := range headers {
+			WithHeader(k, v)(c)
+		}
+	}
+}
+
+// WithUserAgent to set the User-Agent header
+func WithUserAgent(userAgent string) Option {
+	return func(c *Config) {
+		c.UserAgent = userAgent
+	}
+}
+
+// WithRetry to set the retry config
+func WithRetry(retry *RetryConfig) Option {
+	return func(c *Config) {
+		c.Retry = retry
+	}
+}
+
+// WithBreaker to set the circuit breaker config
+func WithBreaker(breaker *BreakerConfig) Option {
+	return func(c *Config) {
+		c.Breaker = breaker
+	}
+}
+
+// WithRateLimit to limit the rate and concurrency of the requests
+func WithRateLimit(rateLimit *RateLimitConfig) Option {
+	return func(c *Config) {
+		c.RateLimit = rateLimit
+	}
+}
+
+// WithCache to cache the responses of GET requests in the storage
+func WithCache(storage CacheStorage) Option {
+	return func(c *Config) {
+		c.Cache = &CacheConfig{Storage: storage}
+	}
+}
+
+// WithAuth to authenticate every request with the provider
+func WithAuth(provider AuthProvider) Option {
+	return func(c *Config) {
+		c.Auth = provider
+	}
+}
+
+// WithLogger to log every request
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// WithClientCertificateFile to set the client certificate from PEM encoded files
+func WithClientCertificateFile(certFile, keyFile string) Option {
+	return func(c *Config) {
+		c.CertFile = certFile
+		c.KeyFile = keyFile
+	}
+}
+
+// WithClientCertificates to set the client certificates
+func WithClientCertificates(certs ...tls.Certificate) Option {
+	return func(c *Config) {
+		c.Certificates = append(c.Certificates, certs...)
+	}
+}
+
+// WithCAFile to trust the CA certificates in the PEM encoded file
+func WithCAFile(caFile string) Option {
+	return func(c *Config) {
+		c.CAFile = caFile
+	}
+}
+
+// WithRootCAs to set the CA pool to verify the servers
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *Config) {
+		c.RootCAs = pool
+	}
+}
+
+// WithInsecureSkipVerify to skip verifying the server certificates, only for testing
+func WithInsecureSkipVerify(skip bool) Option {
+	return func(c *Config) {
+		c.InsecureSkipVerify = skip
+	}
+}
+
+// WithProxy to set the proxy URL
+func WithProxy(proxy string) Option {
+	return func(c *Config) {
+		c.Proxy = proxy
+	}
+}
+
+// WithConnectionPool to set the connection pool sizes of the transport, 0 means using the default value
+func WithConnectionPool(maxIdleConns, maxIdleConnsPerHost, maxConnsPerHost int, idleConnTimeout time.Duration) Option {
+	return func(c *Config) {
+		c.MaxIdleConns = maxIdleConns
+		c.MaxIdleConnsPerHost = maxIdleConnsPerHost
+		c.MaxConnsPerHost = maxConnsPerHost
+		c.IdleConnTimeout = idleConnTimeout
+	}
+}
@@ -0,0 +1,214 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Limit is the rate of a token bucket
+type Limit struct {
+	// Rate is the requests per second, 0 means no limit
+	Rate float64
+	// Burst is the max requests sent at once, it is at least 1
+	Burst int
+}
+
+// RateLimitConfig is the config of client-side rate limiting
+type RateLimitConfig struct {
+	// Global limits the requests to all hosts
+	Global Limit
+	// PerHost limits the requests to every host
+	PerHost Limit
+	// Hosts overrides PerHost for the given hosts, the key is host[:port]
+	Hosts map[string]Limit
+	// MaxConcurrent caps the in-flight requests, 0 means no limit
+	MaxConcurrent int
+}
+
+func loadRateLimitConfig(keys configKeys) *RateLimitConfig {
+	return &RateLimitConfig{
+		Global: Limit{
+			Rate:  viper.GetFloat64(keys.key("RATE_LIMIT")),
+			Burst: viper.GetInt(keys.key("RATE_BURST")),
+		},
+		PerHost: Limit{
+			Rate:  viper.GetFloat64(keys.key("HOST_RATE_LIMIT")),
+			Burst: viper.GetInt(keys.key("HOST_RATE_BURST")),
+		},
+		MaxConcurrent: viper.GetInt(keys.key("MAX_CONCURRENT")),
+	}
+}
+
+func (c *RateLimitConfig) enabled() bool {
+	return c != nil && (c.Global.Rate > 0 || c.PerHost.Rate > 0 || len(c.Hosts) > 0 || c.MaxConcurrent > 0)
+}
+
+// RateLimitMiddleware to get a middleware which limits the rate and concurrency of the requests
+// A request blocks until it gets the tokens, or fails with the error of its context.
+// The concurrency slot is held until the response body is closed.
+func RateLimitMiddleware(cfg *RateLimitConfig) Middleware {
+	l := &limiter{
+		cfg:    *cfg,
+		global: newTokenBucket(cfg.Global),
+		hosts:  map[string]*tokenBucket{},
+	}
+	if cfg.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			// The tokens of both buckets are reserved at once, and given back together if the request gives up
+			now := time.Now()
+			global := l.global.reserve(now)
+			host := l.host(req.URL.Host).reserve(now)
+			at := global.at
+			if host.at.After(at) {
+				at = host.at
+			}
+			if err := waitUntil(ctx, at); err != nil {
+				global.cancel()
+				host.cancel()
+				return nil, err
+			}
+			if l.slots == nil {
+				return next.RoundTrip(req)
+			}
+
+			select {
+			case l.slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			release := func() { <-l.slots }
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				release()
+				return resp, err
+			}
+			resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+			return resp, nil
+		})
+	}
+}
+
+type limiter struct {
+	cfg    RateLimitConfig
+	global *tokenBucket
+	mu     sync.Mutex
+	hosts  map[string]*tokenBucket
+	slots  chan struct{}
+}
+
+func (l *limiter) host(host string) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.hosts[host]
+	if !ok {
+		limit, ok := l.cfg.Hosts[host]
+		if !ok {
+			limit = l.cfg.PerHost
+		}
+		b = newTokenBucket(limit)
+		l.hosts[host] = b
+	}
+	return b
+}
+
+// tokenBucket is a token bucket which lets the waiters go in order
+// A nil bucket means no limit.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit Limit) *tokenBucket {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reservation is a token taken from the bucket, which can be used at the time
+type reservation struct {
+	bucket *tokenBucket
+	at     time.Time
+}
+
+// reserve takes one token, the tokens can go negative which means the time is reserved by the waiters ahead
+func (b *tokenBucket) reserve(now time.Time) reservation {
+	if b == nil {
+		return reservation{at: now}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	b.tokens--
+	at := now
+	if b.tokens < 0 {
+		at = now.Add(time.Duration(-b.tokens / b.rate * float64(time.Second)))
+	}
+	return reservation{bucket: b, at: at}
+}
+
+// cancel gives the token back, it is called if the request is not sent
+func (r reservation) cancel() {
+	if r.bucket == nil {
+		return
+	}
+	r.bucket.mu.Lock()
+	r.bucket.tokens++
+	r.bucket.mu.Unlock()
+}
+
+// waitUntil to wait until the time, or fail with the error of ctx
+func waitUntil(ctx context.Context, at time.Time) error {
+	d := time.Until(at)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
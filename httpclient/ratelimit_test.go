@@ -0,0 +1,142 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(Limit{Rate: 10, Burst: 2})
+	start := b.last
+	tests := []struct {
+		now  time.Duration
+		want time.Duration
+	}{
+		// The burst goes at once
+		{0, 0},
+		{0, 0},
+		// Then one token per 100ms, reserved in order
+		{0, 100 * time.Millisecond},
+		{0, 200 * time.Millisecond},
+		{50 * time.Millisecond, 300 * time.Millisecond},
+		// The tokens are refilled up to the burst
+		{time.Second, time.Second},
+		{time.Second, time.Second},
+		{time.Second, time.Second + 100*time.Millisecond},
+	}
+	for i, tt := range tests {
+		if got := b.reserve(start.Add(tt.now)).at.Sub(start); got != tt.want {
+			t.Errorf("%d: got reservation at %s, want %s", i, got, tt.want)
+		}
+	}
+
+	if b := newTokenBucket(Limit{}); b != nil {
+		t.Errorf("got bucket %+v, want nil without rate", b)
+	}
+	var unlimited *tokenBucket
+	now := time.Now()
+	r := unlimited.reserve(now)
+	r.cancel()
+	if !r.at.Equal(now) {
+		t.Errorf("got reservation at %s, want now without limit", r.at)
+	}
+}
+
+func TestTokenBucketCancel(t *testing.T) {
+	b := newTokenBucket(Limit{Rate: 1, Burst: 1})
+	now := time.Now()
+	b.reserve(now)
+	r := b.reserve(now)
+	if r.at.Sub(now) != time.Second {
+		t.Fatalf("got reservation in %s, want 1s", r.at.Sub(now))
+	}
+	r.cancel()
+	if got := b.reserve(now).at.Sub(now); got != time.Second {
+		t.Errorf("got reservation in %s after the cancel, want the canceled 1s", got)
+	}
+
+	// The token available at once is given back as well
+	b = newTokenBucket(Limit{Rate: 1, Burst: 1})
+	b.reserve(now).cancel()
+	if got := b.reserve(now).at; !got.Equal(now) {
+		t.Errorf("got reservation in %s, want now", got.Sub(now))
+	}
+}
+
+// fakeTransport responds 200 with the body, or the error
+type fakeTransport struct {
+	err error
+}
+
+func (f fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("ok")), Request: req}, nil
+}
+
+// roundTrip to send the request to the url through the transport with the timeout
+func roundTrip(t *testing.T, transport http.RoundTripper, url string, timeout time.Duration) (*http.Response, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transport.RoundTrip(req)
+}
+
+func TestRateLimitCanceledWaitKeepsGlobalToken(t *testing.T) {
+	transport := RateLimitMiddleware(&RateLimitConfig{
+		Global:  Limit{Rate: 1, Burst: 2},
+		PerHost: Limit{Rate: 1, Burst: 1},
+	})(fakeTransport{})
+
+	if _, err := roundTrip(t, transport, "http://a.example.com", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// The host bucket of a is empty, so the request gives up
+	if _, err := roundTrip(t, transport, "http://a.example.com", 20*time.Millisecond); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, want the deadline", err)
+	}
+	// The global token reserved by the canceled request is given back
+	start := time.Now()
+	if _, err := roundTrip(t, transport, "http://b.example.com", 500*time.Millisecond); err != nil {
+		t.Fatalf("got error %v after %s, want the given back global token", err, time.Since(start))
+	}
+}
+
+func TestRateLimitConcurrency(t *testing.T) {
+	transport := RateLimitMiddleware(&RateLimitConfig{MaxConcurrent: 1})(fakeTransport{})
+
+	resp, err := roundTrip(t, transport, "http://a.example.com", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The slot is held until the body is closed
+	if _, err := roundTrip(t, transport, "http://b.example.com", 20*time.Millisecond); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, want the deadline while the slot is held", err)
+	}
+	_ = resp.Body.Close()
+	_ = resp.Body.Close()
+	resp, err = roundTrip(t, transport, "http://b.example.com", time.Second)
+	if err != nil {
+		t.Fatalf("got error %v, want the released slot", err)
+	}
+	_ = resp.Body.Close()
+
+	// The slot is released when the request fails
+	errDown := errors.New("down")
+	failing := RateLimitMiddleware(&RateLimitConfig{MaxConcurrent: 1})(fakeTransport{err: errDown})
+	for i := 0; i < 2; i++ {
+		if _, err := roundTrip(t, failing, "http://a.example.com", 100*time.Millisecond); err != errDown {
+			t.Fatalf("%d: got error %v, want the transport error", i, err)
+		}
+	}
+}
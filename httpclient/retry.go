@@ -0,0 +1,144 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/spf13/viper"
+)
+
+// RetryConfig is the config of request retry
+type RetryConfig struct {
+	// Count is the max number of retries, 0 means no retry
+	Count int `config:"count"`
+	// WaitTime is the initial wait time between two attempts
+	WaitTime time.Duration `config:"wait_time" unit:"ms" default:"100"`
+	// MaxWaitTime caps the wait time of the backoff
+	// The Retry-After header of the server is honored, but a response whose Retry-After is longer
+	// is returned without retry, so that the caller can see it.
+	MaxWaitTime time.Duration `config:"max_wait_time" unit:"ms" default:"2000"`
+	// Retry on the status codes of 5xx
+	OnServerError bool `config:"on_server_error" default:"true"`
+	// Retry on the status code 429
+	OnTooManyRequests bool `config:"on_too_many_requests" default:"true"`
+	// Retry on the errors of connection, such as connection refused or timeout
+	OnConnectionError bool `config:"on_connection_error" default:"true"`
+	// Retry the requests which are not idempotent, such as POST and PATCH, which may be applied twice then
+	// They are retried only if they carry the Idempotency-Key header by default.
+	OnNonIdempotent bool `config:"on_non_idempotent"`
+}
+
+// IdempotencyKeyHeader is the header which marks a request as safe to retry whatever the method is
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultRetryConfig to get the default retry config
+func DefaultRetryConfig() *RetryConfig {
+	return &RetryConfig{
+		Count:             3,
+		WaitTime:          100 * time.Millisecond,
+		MaxWaitTime:       2 * time.Second,
+		OnServerError:     true,
+		OnTooManyRequests: true,
+		OnConnectionError: true,
+	}
+}
+
+func loadRetryConfig(keys configKeys) *RetryConfig {
+	cfg := DefaultRetryConfig()
+	cfg.Count = viper.GetInt(keys.key("RETRY_COUNT"))
+	if v := viper.GetInt64(keys.key("RETRY_WAIT_TIME")); v > 0 {
+		cfg.WaitTime = time.Duration(v) * time.Millisecond
+	}
+	if v := viper.GetInt64(keys.key("RETRY_MAX_WAIT_TIME")); v > 0 {
+		cfg.MaxWaitTime = time.Duration(v) * time.Millisecond
+	}
+	if viper.IsSet(keys.key("RETRY_ON_SERVER_ERROR")) {
+		cfg.OnServerError = viper.GetBool(keys.key("RETRY_ON_SERVER_ERROR"))
+	}
+	if viper.IsSet(keys.key("RETRY_ON_TOO_MANY_REQUESTS")) {
+		cfg.OnTooManyRequests = viper.GetBool(keys.key("RETRY_ON_TOO_MANY_REQUESTS"))
+	}
+	if viper.IsSet(keys.key("RETRY_ON_CONNECTION_ERROR")) {
+		cfg.OnConnectionError = viper.GetBool(keys.key("RETRY_ON_CONNECTION_ERROR"))
+	}
+	cfg.OnNonIdempotent = viper.GetBool(keys.key("RETRY_ON_NON_IDEMPOTENT"))
+	return cfg
+}
+
+func applyRetry(client *resty.Client, cfg *RetryConfig) {
+	if cfg == nil || cfg.Count <= 0 {
+		return
+	}
+	client.SetRetryCount(cfg.Count)
+	if cfg.WaitTime > 0 {
+		client.SetRetryWaitTime(cfg.WaitTime)
+	}
+	maxWaitTime := cfg.MaxWaitTime
+	if maxWaitTime <= 0 {
+		maxWaitTime = DefaultRetryConfig().MaxWaitTime
+	}
+	client.SetRetryMaxWaitTime(maxWaitTime)
+	client.SetRetryAfter(retryAfter)
+	client.AddRetryCondition(func(resp *resty.Response, err error) bool {
+		// The request is not sent if there is no response, such as an error of the middlewares
+		if resp == nil || resp.Request == nil {
+			return false
+		}
+		if !cfg.OnNonIdempotent && !isIdempotent(resp.Request) {
+			return false
+		}
+		if err != nil {
+			// The host is known to be unhealthy, do not hammer it
+			if errors.Is(err, ErrCircuitOpen) {
+				return false
+			}
+			return cfg.OnConnectionError
+		}
+		if parseRetryAfter(resp) > maxWaitTime {
+			return false
+		}
+		code := resp.StatusCode()
+		if code == http.StatusTooManyRequests {
+			return cfg.OnTooManyRequests
+		}
+		return code >= 500 && cfg.OnServerError
+	})
+}
+
+// isIdempotent to check whether the request can be sent again without side effects, see RFC 7231 4.2.2
+func isIdempotent(req *resty.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// retryAfter gets the wait time from the Retry-After header, 0 means using the default exponential backoff
+// The responses whose Retry-After exceeds MaxWaitTime are not retried, see applyRetry.
+func retryAfter(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+	return parseRetryAfter(resp), nil
+}
+
+// parseRetryAfter gets the wait time from the Retry-After header, it can be either seconds or a http date
+func parseRetryAfter(resp *resty.Response) time.Duration {
+	if resp == nil || resp.RawResponse == nil {
+		return 0
+	}
+	v := resp.Header().Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
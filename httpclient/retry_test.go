@@ -0,0 +1,118 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+func TestRetryIdempotentOnly(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name            string
+		method          string
+		idempotencyKey  string
+		onNonIdempotent bool
+		want            int32
+	}{
+		{"get", http.MethodGet, "", false, 3},
+		{"put", http.MethodPut, "", false, 3},
+		{"post", http.MethodPost, "", false, 1},
+		{"patch", http.MethodPatch, "", false, 1},
+		{"post with idempotency key", http.MethodPost, "order-1", false, 3},
+		{"post on non idempotent", http.MethodPost, "", true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&attempts, 0)
+			retry := DefaultRetryConfig()
+			retry.Count = 2
+			retry.WaitTime = time.Millisecond
+			retry.MaxWaitTime = time.Millisecond
+			retry.OnNonIdempotent = tt.onNonIdempotent
+			client := NewClient(&Config{Timeout: time.Second, Retry: retry})
+
+			req := client.R()
+			if tt.idempotencyKey != "" {
+				req.SetHeader(IdempotencyKeyHeader, tt.idempotencyKey)
+			}
+			if _, err := req.Execute(tt.method, ts.URL); err != nil {
+				t.Fatal(err)
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.want {
+				t.Errorf("got %d attempts, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		want       int32
+	}{
+		{"within max wait time", "0", 3},
+		{"exceeds max wait time", "5", 1},
+		{"http date exceeds max wait time", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				w.Header().Set("Retry-After", tt.retryAfter)
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
+			defer ts.Close()
+
+			retry := DefaultRetryConfig()
+			retry.Count = 2
+			retry.WaitTime = time.Millisecond
+			retry.MaxWaitTime = time.Second
+			resp, err := NewClient(&Config{Timeout: time.Second, Retry: retry}).R().Get(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode() != http.StatusTooManyRequests || resp.Header().Get("Retry-After") != tt.retryAfter {
+				t.Errorf("got %d with Retry-After %q, want the response of the server", resp.StatusCode(), resp.Header().Get("Retry-After"))
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.want {
+				t.Errorf("got %d attempts, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"", 0, 0},
+		{"3", 3 * time.Second, 3 * time.Second},
+		{"soon", 0, 0},
+		{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		resp := &resty.Response{RawResponse: &http.Response{Header: http.Header{}}}
+		if tt.value != "" {
+			resp.RawResponse.Header.Set("Retry-After", tt.value)
+		}
+		if got := parseRetryAfter(resp); got < tt.min || got > tt.max {
+			t.Errorf("Retry-After %q: got %s, want between %s and %s", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
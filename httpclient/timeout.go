@@ -0,0 +1,68 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/uhhc/sdk-common-go/config"
+)
+
+// DynamicTimeout is a request timeout which can be changed while the client is in use,
+// such as when the config is reloaded
+type DynamicTimeout struct {
+	timeout int64
+}
+
+// NewDynamicTimeout to get a dynamic timeout, 0 means no timeout
+func NewDynamicTimeout(timeout time.Duration) *DynamicTimeout {
+	return &DynamicTimeout{timeout: int64(timeout)}
+}
+
+// Set to change the timeout, it applies to the requests sent after it
+func (t *DynamicTimeout) Set(timeout time.Duration) {
+	atomic.StoreInt64(&t.timeout, int64(timeout))
+}
+
+// Get to get the timeout
+func (t *DynamicTimeout) Get() time.Duration {
+	return time.Duration(atomic.LoadInt64(&t.timeout))
+}
+
+// Watch to set the timeout when the key is reloaded by the watcher, such as "http_client.timeout",
+// the key should be a time.Duration field like Config.Timeout
+// Example:
+//
+// 		timeout := httpclient.NewDynamicTimeout(cfg.HTTP.Timeout)
+// 		timeout.Watch(w, "http_client.timeout")
+// 		client, err := httpclient.NewClientWithOptions(httpclient.WithDynamicTimeout(timeout))
+//
+func (t *DynamicTimeout) Watch(w *config.Watcher, key string) {
+	w.SubscribeValues(func(values map[string]interface{}) {
+		if timeout, ok := values[key].(time.Duration); ok {
+			t.Set(timeout)
+		}
+	}, key)
+}
+
+// TimeoutMiddleware to get a middleware which limits the time of every attempt by the dynamic timeout
+// Like the timeout of http.Client, it includes reading the response body.
+func TimeoutMiddleware(timeout *DynamicTimeout) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			d := timeout.Get()
+			if d <= 0 {
+				return next.RoundTrip(req)
+			}
+			ctx, cancel := context.WithTimeout(req.Context(), d)
+			resp, err := next.RoundTrip(req.WithContext(ctx))
+			if err != nil {
+				cancel()
+				return resp, err
+			}
+			resp.Body = &releaseBody{ReadCloser: resp.Body, release: cancel}
+			return resp, nil
+		})
+	}
+}
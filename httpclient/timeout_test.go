@@ -0,0 +1,44 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/uhhc/sdk-common-go/config"
+)
+
+func TestDynamicTimeoutWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpclient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(file, []byte("http_client:\n  timeout: 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg struct {
+		HTTP Config `config:"http_client"`
+	}
+	w, err := config.NewLoader(config.WithFile(file)).Watch(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	timeout := NewDynamicTimeout(cfg.HTTP.Timeout)
+	timeout.Watch(w, "http_client.timeout")
+
+	if err := ioutil.WriteFile(file, []byte("http_client:\n  timeout: 500ms\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := timeout.Get(); got != 500*time.Millisecond {
+		t.Errorf("got timeout %s, want 500ms", got)
+	}
+}
@@ -0,0 +1,35 @@
+package httpclient
+
+import (
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Middleware wraps the transport of the http client
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an adapter to use a function as http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Use to wrap the transport of the client with middlewares, the first one is the outermost
+// Note that resty can not change the TLS or proxy settings of a wrapped transport any more,
+// so set them before calling Use.
+func Use(client *resty.Client, middlewares ...Middleware) *resty.Client {
+	if len(middlewares) == 0 {
+		return client
+	}
+	transport := client.GetClient().Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		transport = middlewares[i](transport)
+	}
+	return client.SetTransport(transport)
+}
@@ -0,0 +1,210 @@
+package log
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Default settings of the asynchronous writing
+const (
+	DefaultAsyncBufferSize    = 256 * 1024
+	DefaultAsyncFlushInterval = time.Second
+)
+
+// Overflow policies of the asynchronous writing
+const (
+	// OverflowBlock blocks the logging until the buffer has room
+	OverflowBlock = "block"
+	// OverflowDrop drops the entries and counts them
+	OverflowDrop = "drop"
+)
+
+type asyncConfig struct {
+	bufferSize    int
+	flushInterval time.Duration
+	drop          bool
+}
+
+// asyncSinks keeps one writer per output paths and config
+var asyncSinks = struct {
+	sync.Mutex
+	writers map[string]*asyncWriter
+	byURL   map[string]*asyncWriter
+}{writers: map[string]*asyncWriter{}, byURL: map[string]*asyncWriter{}}
+
+// registerAsyncSink to register a zap sink which writes to the paths asynchronously, and get the sink URL
+func registerAsyncSink(paths []string, cfg asyncConfig) (string, error) {
+	key := fmt.Sprintf("%s|%d|%s|%t", strings.Join(paths, ","), cfg.bufferSize, cfg.flushInterval, cfg.drop)
+
+	asyncSinks.Lock()
+	defer asyncSinks.Unlock()
+
+	if w, ok := asyncSinks.writers[key]; ok {
+		return w.url, nil
+	}
+	out, _, err := zap.Open(paths...)
+	if err != nil {
+		return "", err
+	}
+	scheme := fmt.Sprintf("async-%d", len(asyncSinks.writers)+1)
+	w := newAsyncWriter(out, cfg)
+	w.url = scheme + "://"
+	err = zap.RegisterSink(scheme, func(*url.URL) (zap.Sink, error) {
+		return w, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	asyncSinks.writers[key] = w
+	asyncSinks.byURL[w.url] = w
+	return w.url, nil
+}
+
+// flushAsyncSinks to write the buffered entries of all asynchronous writers
+func flushAsyncSinks() error {
+	asyncSinks.Lock()
+	writers := make([]*asyncWriter, 0, len(asyncSinks.writers))
+	for _, w := range asyncSinks.writers {
+		writers = append(writers, w)
+	}
+	asyncSinks.Unlock()
+
+	var firstErr error
+	for _, w := range writers {
+		if err := w.Sync(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// asyncWriterOf to get the asynchronous writer of the output paths, nil if there is none
+func asyncWriterOf(paths []string) *asyncWriter {
+	asyncSinks.Lock()
+	defer asyncSinks.Unlock()
+	for _, p := range paths {
+		if w, ok := asyncSinks.byURL[p]; ok {
+			return w
+		}
+	}
+	return nil
+}
+
+// asyncWriter buffers the encoded entries and writes them in background
+// The buffer is written every flush interval, or at once when it is half full.
+type asyncWriter struct {
+	out     zapcore.WriteSyncer
+	cfg     asyncConfig
+	url     string
+	mu      sync.Mutex
+	cond    *sync.Cond
+	buf     []byte
+	kick    chan struct{}
+	writeMu sync.Mutex
+	dropped uint64
+}
+
+func newAsyncWriter(out zapcore.WriteSyncer, cfg asyncConfig) *asyncWriter {
+	w := &asyncWriter{
+		out:  out,
+		cfg:  cfg,
+		buf:  make([]byte, 0, cfg.bufferSize),
+		kick: make(chan struct{}, 1),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// Write implements zap.Sink, p is copied since zap reuses it
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	for len(w.buf) > 0 && len(w.buf)+len(p) > w.cfg.bufferSize {
+		if w.cfg.drop {
+			w.mu.Unlock()
+			atomic.AddUint64(&w.dropped, 1)
+			return len(p), nil
+		}
+		w.signal()
+		w.cond.Wait()
+	}
+	w.buf = append(w.buf, p...)
+	half := len(w.buf) >= w.cfg.bufferSize/2
+	w.mu.Unlock()
+
+	if half {
+		w.signal()
+	}
+	return len(p), nil
+}
+
+// Sync implements zap.Sink, it writes all buffered entries and syncs the outputs
+func (w *asyncWriter) Sync() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.out.Sync()
+}
+
+// Close implements zap.Sink, the writer is kept since it is shared by the loggers
+func (w *asyncWriter) Close() error {
+	return w.Sync()
+}
+
+// Dropped to get the amount of the dropped entries
+func (w *asyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+func (w *asyncWriter) signal() {
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (w *asyncWriter) run() {
+	ticker := time.NewTicker(w.cfg.flushInterval)
+	defer ticker.Stop()
+	var reported uint64
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.kick:
+		}
+		if err := w.flush(); err != nil {
+			internalErrors.report("async-write:"+w.url, "write logs error: %v", err)
+		}
+		// The dropped entries are counted until they are reported
+		if dropped := w.Dropped(); dropped > reported &&
+			internalErrors.report("async-drop:"+w.url, "dropped %d log entries since the buffer is full", dropped-reported) {
+			reported = dropped
+		}
+	}
+}
+
+// flush writes the buffered entries, the writes are serialized to keep the order
+func (w *asyncWriter) flush() error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+
+	w.mu.Lock()
+	if len(w.buf) == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	data := w.buf
+	w.buf = make([]byte, 0, w.cfg.bufferSize)
+	w.cond.Broadcast()
+	w.mu.Unlock()
+
+	_, err := w.out.Write(data)
+	return err
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFlushWritesBufferedLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "async.log")
+	async := true
+	logger := NewLogger(&Option{
+		LogLevel:           "info",
+		OutputPaths:        []string{file},
+		Async:              &async,
+		AsyncFlushInterval: 60 * 1000,
+	})
+
+	logger.Infow("buffered", "key", "value")
+	b, _ := ioutil.ReadFile(file)
+	if strings.Contains(string(b), "buffered") {
+		t.Fatalf("log is written before flushing: %s", b)
+	}
+
+	if err := Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+	b, err = ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"msg":"buffered"`) || !strings.Contains(string(b), `"key":"value"`) {
+		t.Errorf("log is not flushed: %s", b)
+	}
+}
@@ -0,0 +1,128 @@
+package log
+
+import (
+	"context"
+)
+
+// Keys of the request-scoped fields
+const (
+	RequestIDKey = "requestId"
+	TraceIDKey   = "traceId"
+	UserIDKey    = "userId"
+)
+
+type loggerContextKey struct{}
+
+type fieldsContextKey struct{}
+
+// WithContext to get a context carrying the logger
+// Example:
+//
+// 		ctx = log.WithContext(ctx, logger)
+// 		ctx = log.WithRequestID(ctx, r.Header.Get("X-Request-Id"))
+// 		...
+// 		log.FromContext(ctx).Infow("done")	// with "requestId" field
+//
+func WithContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// FromContext to get the logger from the context with the request-scoped fields
+// It returns nil if there is no logger in the context.
+func FromContext(ctx context.Context) *Logger {
+	if ctx == nil {
+		return nil
+	}
+	logger, ok := ctx.Value(loggerContextKey{}).(*Logger)
+	if !ok || logger == nil {
+		return nil
+	}
+	return logger.withContextFields(ctx)
+}
+
+// ContextLogger to get the logger from the context, or the fallback logger if there is none
+// The request-scoped fields of the context are added in both cases.
+func ContextLogger(ctx context.Context, fallback *Logger) *Logger {
+	if logger := FromContext(ctx); logger != nil {
+		return logger
+	}
+	if fallback == nil || fallback.SugaredLogger == nil || ctx == nil {
+		return fallback
+	}
+	return fallback.withContextFields(ctx)
+}
+
+// WithRequestID to get a context carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return WithField(ctx, RequestIDKey, requestID)
+}
+
+// WithTraceID to get a context carrying the trace ID
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return WithField(ctx, TraceIDKey, traceID)
+}
+
+// WithUserID to get a context carrying the user ID
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return WithField(ctx, UserIDKey, userID)
+}
+
+// WithField to get a context carrying a request-scoped field, which will be added to the context logger
+func WithField(ctx context.Context, key string, value interface{}) context.Context {
+	parent, _ := ctx.Value(fieldsContextKey{}).([]interface{})
+	fields := make([]interface{}, 0, len(parent)+2)
+	for i := 0; i+1 < len(parent); i += 2 {
+		// Replace the field with the same key
+		if parent[i] != key {
+			fields = append(fields, parent[i], parent[i+1])
+		}
+	}
+	fields = append(fields, key, value)
+	return context.WithValue(ctx, fieldsContextKey{}, fields)
+}
+
+// RequestIDFromContext to get the request ID from the context
+func RequestIDFromContext(ctx context.Context) string {
+	return stringField(ctx, RequestIDKey)
+}
+
+// TraceIDFromContext to get the trace ID from the context
+func TraceIDFromContext(ctx context.Context) string {
+	return stringField(ctx, TraceIDKey)
+}
+
+// UserIDFromContext to get the user ID from the context
+func UserIDFromContext(ctx context.Context) string {
+	return stringField(ctx, UserIDKey)
+}
+
+// ContextFields to get all request-scoped fields in the context as key/value pairs
+func ContextFields(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsContextKey{}).([]interface{})
+	return fields
+}
+
+func stringField(ctx context.Context, key string) string {
+	fields := ContextFields(ctx)
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == key {
+			if s, ok := fields[i+1].(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func (sl *Logger) withContextFields(ctx context.Context) *Logger {
+	fields := ContextFields(ctx)
+	if len(fields) == 0 {
+		return sl
+	}
+	logger := *sl
+	logger.SugaredLogger = sl.With(fields...)
+	return &logger
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestContextFields(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithTraceID(ctx, "trace-1")
+	ctx = WithUserID(ctx, "user-1")
+	ctx = WithField(ctx, "tenant", 42)
+	// The field of the same key is replaced
+	ctx = WithRequestID(ctx, "req-2")
+
+	if got := RequestIDFromContext(ctx); got != "req-2" {
+		t.Errorf("got request ID %q, want req-2", got)
+	}
+	if got := TraceIDFromContext(ctx); got != "trace-1" {
+		t.Errorf("got trace ID %q, want trace-1", got)
+	}
+	if got := UserIDFromContext(ctx); got != "user-1" {
+		t.Errorf("got user ID %q, want user-1", got)
+	}
+	want := []interface{}{TraceIDKey, "trace-1", UserIDKey, "user-1", "tenant", 42, RequestIDKey, "req-2"}
+	got := ContextFields(ctx)
+	if len(got) != len(want) {
+		t.Fatalf("got fields %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got fields %v, want %v", got, want)
+			break
+		}
+	}
+
+	// The parent context is not changed
+	parent := WithRequestID(context.Background(), "parent")
+	_ = WithRequestID(parent, "child")
+	if got := RequestIDFromContext(parent); got != "parent" {
+		t.Errorf("got request ID %q of the parent, want parent", got)
+	}
+
+	if got := RequestIDFromContext(context.Background()); got != "" {
+		t.Errorf("got request ID %q, want empty", got)
+	}
+	if got := ContextFields(nil); got != nil {
+		t.Errorf("got fields %v of nil context, want nil", got)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := NewLoggerWithCore(core, zapcore.InfoLevel)
+
+	if got := FromContext(context.Background()); got != nil {
+		t.Errorf("got logger %v, want nil", got)
+	}
+	if got := FromContext(nil); got != nil {
+		t.Errorf("got logger %v of nil context, want nil", got)
+	}
+
+	ctx := WithContext(context.Background(), logger)
+	if got := FromContext(ctx); got != logger {
+		t.Errorf("got logger %p, want the logger %p without fields", got, logger)
+	}
+	FromContext(WithRequestID(ctx, "req-1")).Info("with fields")
+	entries := logs.FilterMessage("with fields").FilterField(zap.String(RequestIDKey, "req-1"))
+	if entries.Len() != 1 {
+		t.Errorf("got entries %v, want the request ID", logs.All())
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	fallback := NewLoggerWithCore(core, zapcore.InfoLevel)
+	ctxCore, ctxLogs := observer.New(zapcore.DebugLevel)
+	ctxLogger := NewLoggerWithCore(ctxCore, zapcore.InfoLevel)
+
+	ctx := WithTraceID(context.Background(), "trace-1")
+	ContextLogger(ctx, fallback).Info("fallback")
+	ContextLogger(WithContext(ctx, ctxLogger), fallback).Info("context")
+	if logs.FilterMessage("fallback").FilterField(zap.String(TraceIDKey, "trace-1")).Len() != 1 {
+		t.Errorf("got entries %v of the fallback, want the trace ID", logs.All())
+	}
+	if ctxLogs.FilterMessage("context").FilterField(zap.String(TraceIDKey, "trace-1")).Len() != 1 || logs.FilterMessage("context").Len() != 0 {
+		t.Errorf("got entries %v of the context logger, want the trace ID", ctxLogs.All())
+	}
+
+	if got := ContextLogger(ctx, nil); got != nil {
+		t.Errorf("got logger %v, want nil without the fallback", got)
+	}
+	zero := &Logger{}
+	if got := ContextLogger(ctx, zero); got != zero {
+		t.Errorf("got logger %v, want the zero fallback as is", got)
+	}
+}
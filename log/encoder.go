@@ -6,8 +6,12 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-func timeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-	enc.AppendString(t.Format("2006-01-02 15:04:05.000"))
+const defaultTimeFormat = "2006-01-02 15:04:05.000"
+
+func newTimeEncoder(layout string, loc *time.Location) zapcore.TimeEncoder {
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.In(loc).Format(layout))
+	}
 }
 
 func milliSecondsDurationEncoder(d time.Duration, enc zapcore.PrimitiveArrayEncoder) {
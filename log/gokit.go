@@ -0,0 +1,95 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Keys of go-kit log which are lifted into the zap entry
+const (
+	gokitLevelKey  = "level"
+	gokitMsgKey    = "msg"
+	gokitCallerKey = "caller"
+)
+
+// gokitMissingValue is the value of the last key when the length of key/values is odd, the same as go-kit
+const gokitMissingValue = "(MISSING)"
+
+// logGokit writes the go-kit key/values with the zap logger
+// The level is info if there is no go-kit level key.
+func logGokit(logger *zap.Logger, kv []interface{}) {
+	if len(kv)%2 != 0 {
+		kv = append(kv, gokitMissingValue)
+	}
+
+	var (
+		level  = zapcore.InfoLevel
+		msg    string
+		caller string
+		fields = make([]zap.Field, 0, len(kv)/2)
+	)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		value := kv[i+1]
+		switch key {
+		case gokitLevelKey:
+			level = gokitLevel(value)
+		case gokitMsgKey:
+			msg = fmt.Sprint(value)
+		case gokitCallerKey:
+			caller = fmt.Sprint(value)
+		default:
+			fields = append(fields, zap.Any(key, value))
+		}
+	}
+
+	// Skip logGokit and the Log method
+	ce := logger.WithOptions(zap.AddCallerSkip(2)).Check(level, msg)
+	if ce == nil {
+		return
+	}
+	if caller != "" {
+		ce.Entry.Caller = parseCaller(caller)
+	}
+	ce.Write(fields...)
+}
+
+// gokitLevel converts go-kit level value, such as level.InfoValue(), to zap level
+func gokitLevel(value interface{}) zapcore.Level {
+	switch strings.ToLower(fmt.Sprint(value)) {
+	case "debug":
+		return zapcore.DebugLevel
+	case "warn", "warning":
+		return zapcore.WarnLevel
+	case "error":
+		return zapcore.ErrorLevel
+	case "dpanic":
+		return zapcore.DPanicLevel
+	case "panic":
+		return zapcore.PanicLevel
+	case "fatal":
+		return zapcore.FatalLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// parseCaller parses go-kit caller like "file.go:12"
+func parseCaller(caller string) zapcore.EntryCaller {
+	idx := strings.LastIndex(caller, ":")
+	if idx < 0 {
+		return zapcore.EntryCaller{Defined: true, File: caller}
+	}
+	line, err := strconv.Atoi(caller[idx+1:])
+	if err != nil {
+		return zapcore.EntryCaller{Defined: true, File: caller}
+	}
+	return zapcore.EntryCaller{Defined: true, File: caller[:idx], Line: line}
+}
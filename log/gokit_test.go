@@ -0,0 +1,113 @@
+package log
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newObservedLogger to get a Logger which records the entries at or above the level
+func newObservedLogger(level zapcore.Level) (Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(level)
+	return Logger{SugaredLogger: zap.New(core).Sugar()}, logs
+}
+
+func TestGokitFields(t *testing.T) {
+	logger, logs := newObservedLogger(zapcore.DebugLevel)
+	_ = logger.Log("msg", "created", "id", 42, "name", "order", 7, "non-string key")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Message != "created" || entry.Level != zapcore.InfoLevel {
+		t.Errorf("got %s %q, want info %q", entry.Level, entry.Message, "created")
+	}
+	want := map[string]interface{}{"id": int64(42), "name": "order", "7": "non-string key"}
+	got := entry.ContextMap()
+	if len(got) != len(want) {
+		t.Errorf("got fields %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("got field %s=%v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func TestGokitLevel(t *testing.T) {
+	tests := []struct {
+		level interface{}
+		want  zapcore.Level
+	}{
+		{"debug", zapcore.DebugLevel},
+		{"info", zapcore.InfoLevel},
+		{"warn", zapcore.WarnLevel},
+		{"WARNING", zapcore.WarnLevel},
+		{"error", zapcore.ErrorLevel},
+		{"unknown", zapcore.InfoLevel},
+		{gokitLevelValue("error"), zapcore.ErrorLevel},
+	}
+	for _, tt := range tests {
+		logger, logs := newObservedLogger(zapcore.DebugLevel)
+		_ = logger.Log("level", tt.level, "msg", "leveled")
+		entries := logs.AllUntimed()
+		if len(entries) != 1 {
+			t.Fatalf("level %v: got %d entries, want 1", tt.level, len(entries))
+		}
+		if entries[0].Level != tt.want {
+			t.Errorf("level %v: got %s, want %s", tt.level, entries[0].Level, tt.want)
+		}
+		if _, ok := entries[0].ContextMap()["level"]; ok {
+			t.Errorf("level %v: the level key is logged as a field", tt.level)
+		}
+	}
+}
+
+func TestGokitLevelFiltered(t *testing.T) {
+	logger, logs := newObservedLogger(zapcore.WarnLevel)
+	_ = logger.Log("level", "debug", "msg", "dropped")
+	_ = logger.Log("msg", "dropped too")
+	_ = logger.Log("level", "warn", "msg", "kept")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 || entries[0].Message != "kept" {
+		t.Errorf("got %v, want only the warn entry", entries)
+	}
+}
+
+func TestGokitOddKeyvals(t *testing.T) {
+	logger, logs := newObservedLogger(zapcore.DebugLevel)
+	_ = logger.Log("msg", "odd", "dangling")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if got := entries[0].ContextMap()["dangling"]; got != gokitMissingValue {
+		t.Errorf("got dangling=%v, want %q", got, gokitMissingValue)
+	}
+}
+
+func TestGokitCaller(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := OriginLogger{Logger: zap.New(core, zap.AddCaller())}
+	_ = logger.Log("caller", "service.go:12", "msg", "with caller")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	caller := entries[0].Caller
+	if caller.File != "service.go" || caller.Line != 12 {
+		t.Errorf("got caller %s, want service.go:12", caller.String())
+	}
+}
+
+// gokitLevelValue is like the values of go-kit level.InfoValue(), which are fmt.Stringer
+type gokitLevelValue string
+
+func (v gokitLevelValue) String() string { return string(v) }
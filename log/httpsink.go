@@ -0,0 +1,105 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Schemes of the HTTP sinks, the entries are posted as a JSON array to the URL without the "httplog+" prefix
+// Example:
+//
+// 		httplog+https://collector.example.com/v1/logs?batch=100&interval=1000&header=X-Token:abc
+//
+const (
+	HTTPLogScheme  = "httplog+http"
+	HTTPSLogScheme = "httplog+https"
+)
+
+func init() {
+	registerRemoteSink([]string{HTTPLogScheme, HTTPSLogScheme}, newHTTPSender)
+}
+
+// httpSender posts the batches of entries
+type httpSender struct {
+	url    string
+	header http.Header
+	client *http.Client
+}
+
+func newHTTPSender(u *url.URL, opts remoteOptions) (func([]remoteEntry) error, error) {
+	target := *u
+	target.Scheme = strings.TrimPrefix(u.Scheme, "httplog+")
+
+	q := target.Query()
+	header := http.Header{}
+	for _, h := range q["header"] {
+		kv := strings.SplitN(h, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("log: invalid header %q of %s, it should be key:value", h, remoteName(u))
+		}
+		header.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+	q.Del("header")
+	target.RawQuery = q.Encode()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = opts.tls
+	s := &httpSender{
+		url:    target.String(),
+		header: header,
+		client: &http.Client{Timeout: opts.timeout, Transport: transport},
+	}
+	return s.send, nil
+}
+
+// send posts the entries, the entries which are not JSON are sent as JSON strings
+// The 4xx responses except 429 are not retried since they would fail again.
+func (s *httpSender) send(batch []remoteEntry) error {
+	var body bytes.Buffer
+	body.WriteByte('[')
+	for i, e := range batch {
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		entry := bytes.TrimRight(e.data, "\r\n")
+		if json.Valid(entry) {
+			body.Write(entry)
+		} else {
+			b, _ := json.Marshal(string(entry))
+			body.Write(b)
+		}
+	}
+	body.WriteByte(']')
+
+	req, err := http.NewRequest(http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	for k, v := range s.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("log: post logs status %d", resp.StatusCode)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		fmt.Fprintf(os.Stderr, "%v, %d log entries are dropped\n", err, len(batch))
+		return nil
+	}
+	return err
+}
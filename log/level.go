@@ -0,0 +1,243 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelRegistry keeps the levels of a root logger and its named loggers
+type levelRegistry struct {
+	root  zap.AtomicLevel
+	mu    sync.Mutex
+	named map[string]*namedLevel
+	// saved is the root level before switching to debug by signal
+	saved *zapcore.Level
+}
+
+func newLevelRegistry(root zap.AtomicLevel) *levelRegistry {
+	return &levelRegistry{
+		root:  root,
+		named: map[string]*namedLevel{},
+	}
+}
+
+// namedLevel is the level of a named logger, it follows the root level until it is set
+type namedLevel struct {
+	root  zap.AtomicLevel
+	level zap.AtomicLevel
+	isSet int32
+}
+
+// Enabled implements zapcore.LevelEnabler
+func (l *namedLevel) Enabled(lvl zapcore.Level) bool {
+	if atomic.LoadInt32(&l.isSet) == 0 {
+		return l.root.Enabled(lvl)
+	}
+	return l.level.Enabled(lvl)
+}
+
+func (l *namedLevel) get() (zapcore.Level, bool) {
+	if atomic.LoadInt32(&l.isSet) == 0 {
+		return l.root.Level(), false
+	}
+	return l.level.Level(), true
+}
+
+func (l *namedLevel) set(lvl zapcore.Level) {
+	l.level.SetLevel(lvl)
+	atomic.StoreInt32(&l.isSet, 1)
+}
+
+func (l *namedLevel) reset() {
+	atomic.StoreInt32(&l.isSet, 0)
+}
+
+func (r *levelRegistry) namedLevel(name string) *namedLevel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.named[name]
+	if !ok {
+		l = &namedLevel{root: r.root, level: zap.NewAtomicLevel()}
+		r.named[name] = l
+	}
+	return l
+}
+
+// wrapCore to get the zap option which checks the level of the named logger
+func (r *levelRegistry) wrapCore(name string) zap.Option {
+	enabler := r.namedLevel(name)
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if c, ok := core.(*levelCore); ok {
+			core = c.Core
+		}
+		return &levelCore{Core: core, enabler: enabler}
+	})
+}
+
+// levelCore checks the level by its own enabler, the wrapped core is built with the lowest level,
+// so that the named loggers can log at a lower level than the root logger.
+type levelCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+// Enabled implements zapcore.Core
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.enabler.Enabled(lvl)
+}
+
+// With implements zapcore.Core
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+// Check implements zapcore.Core
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabler.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// errNoLevel is returned when the logger is not created by NewLogger or NewLoggerWithCore, such as a zero Logger
+var errNoLevel = errors.New("log: the logger has no adjustable level")
+
+// levelHandler to get the level handler of the registry, which responds 501 if the registry is nil
+func levelHandler(r *levelRegistry) http.Handler {
+	if r == nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			writeLevelPayload(w, http.StatusNotImplemented, levelPayload{Error: errNoLevel.Error()})
+		})
+	}
+	return r
+}
+
+// watchLevelSignals to watch the signals for the registry, it does nothing if the registry is nil
+func watchLevelSignals(r *levelRegistry) (stop func()) {
+	if r == nil {
+		return func() {}
+	}
+	return r.watchLevelSignals()
+}
+
+// setLevel to set the root level, the zero AtomicLevel can not be set
+func setLevel(root zap.AtomicLevel, level string) error {
+	if root == (zap.AtomicLevel{}) {
+		return errNoLevel
+	}
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return fmt.Errorf("log: %w", err)
+	}
+	root.SetLevel(lvl)
+	return nil
+}
+
+// levelPayload is the body of the level handler
+type levelPayload struct {
+	Level   string            `json:"level,omitempty"`
+	Logger  string            `json:"logger,omitempty"`
+	Loggers map[string]string `json:"loggers,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// ServeHTTP implements http.Handler
+// GET returns the levels, PUT changes the level of the root logger, or the named logger if "logger" is given.
+// Setting an empty level to a named logger makes it follow the root level again.
+func (r *levelRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		writeLevelPayload(w, http.StatusOK, r.payload())
+	case http.MethodPut:
+		var p levelPayload
+		if err := json.NewDecoder(req.Body).Decode(&p); err != nil {
+			writeLevelPayload(w, http.StatusBadRequest, levelPayload{Error: fmt.Sprintf("invalid body: %v", err)})
+			return
+		}
+		if err := r.setLevel(p.Logger, p.Level); err != nil {
+			writeLevelPayload(w, http.StatusBadRequest, levelPayload{Error: err.Error()})
+			return
+		}
+		writeLevelPayload(w, http.StatusOK, r.payload())
+	default:
+		writeLevelPayload(w, http.StatusMethodNotAllowed, levelPayload{Error: "only GET and PUT are supported"})
+	}
+}
+
+func (r *levelRegistry) payload() levelPayload {
+	p := levelPayload{
+		Level:   r.root.Level().String(),
+		Loggers: map[string]string{},
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, l := range r.named {
+		lvl, _ := l.get()
+		p.Loggers[name] = lvl.String()
+	}
+	return p
+}
+
+func (r *levelRegistry) setLevel(name, level string) error {
+	if name != "" && level == "" {
+		r.namedLevel(name).reset()
+		return nil
+	}
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		r.root.SetLevel(lvl)
+	} else {
+		r.namedLevel(name).set(lvl)
+	}
+	return nil
+}
+
+func writeLevelPayload(w http.ResponseWriter, status int, p levelPayload) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// parseLevel parses the level strictly, both "warning" and "warn" are accepted
+func parseLevel(level string) (zapcore.Level, error) {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "warning" {
+		return zapcore.WarnLevel, nil
+	}
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return lvl, fmt.Errorf("invalid level %q", level)
+	}
+	return lvl, nil
+}
+
+// toggleDebug switches the root level to debug, or back to the level before
+func (r *levelRegistry) toggleDebug(debug bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if debug {
+		if r.saved == nil {
+			lvl := r.root.Level()
+			r.saved = &lvl
+		}
+		r.root.SetLevel(zapcore.DebugLevel)
+		return
+	}
+	if r.saved != nil {
+		r.root.SetLevel(*r.saved)
+		r.saved = nil
+	}
+}
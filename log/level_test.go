@@ -0,0 +1,126 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// serveLevel to send the request to the handler, and get the status and the payload
+func serveLevel(t *testing.T, h http.Handler, method, body string) (int, levelPayload) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, "/log/level", strings.NewReader(body)))
+	var p levelPayload
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode %q error: %v", rec.Body.String(), err)
+	}
+	return rec.Code, p
+}
+
+func TestLevelHandler(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := NewLoggerWithCore(core, zapcore.InfoLevel)
+	db := logger.NamedLogger("db")
+	h := logger.LevelHandler()
+
+	tests := []struct {
+		method     string
+		body       string
+		wantStatus int
+		wantLevel  string
+		wantDB     string
+	}{
+		{http.MethodGet, "", http.StatusOK, "info", "info"},
+		{http.MethodPut, `{"logger": "db", "level": "debug"}`, http.StatusOK, "info", "debug"},
+		{http.MethodPut, `{"level": "WARNING"}`, http.StatusOK, "warn", "debug"},
+		{http.MethodPut, `{"logger": "db"}`, http.StatusOK, "warn", "warn"},
+		{http.MethodPut, `{"level": "verbose"}`, http.StatusBadRequest, "", ""},
+		{http.MethodPut, `level=debug`, http.StatusBadRequest, "", ""},
+		{http.MethodPost, "", http.StatusMethodNotAllowed, "", ""},
+	}
+	for _, tt := range tests {
+		status, p := serveLevel(t, h, tt.method, tt.body)
+		if status != tt.wantStatus || p.Level != tt.wantLevel || p.Loggers["db"] != tt.wantDB {
+			t.Errorf("%s %s: got %d %+v, want %d level %q db %q", tt.method, tt.body, status, p, tt.wantStatus, tt.wantLevel, tt.wantDB)
+		}
+		if tt.wantStatus != http.StatusOK && p.Error == "" {
+			t.Errorf("%s %s: got no error in %+v", tt.method, tt.body, p)
+		}
+	}
+
+	// The named logger follows the root level again after the reset
+	_, _ = serveLevel(t, h, http.MethodPut, `{"logger": "db", "level": "debug"}`)
+	db.Debug("db debug")
+	logger.Info("root info")
+	if logs.FilterMessage("db debug").Len() != 1 || logs.FilterMessage("root info").Len() != 0 {
+		t.Errorf("got entries %v, want the db debug only", logs.All())
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	core, _ := observer.New(zapcore.DebugLevel)
+	logger := NewLoggerWithCore(core, zapcore.InfoLevel)
+	if err := logger.SetLevel("error"); err != nil {
+		t.Fatal(err)
+	}
+	if logger.AtomicLevel.Level() != zapcore.ErrorLevel {
+		t.Errorf("got level %s, want error", logger.AtomicLevel.Level())
+	}
+	if err := logger.SetLevel("verbose"); err == nil {
+		t.Error("got no error, want the invalid level")
+	}
+
+	origin := NewOriginLogger(&Option{LogLevel: "info", OutputPaths: []string{"stderr"}})
+	if err := origin.SetLevel("warn"); err != nil || origin.AtomicLevel.Level() != zapcore.WarnLevel {
+		t.Errorf("got level %s and error %v, want warn", origin.AtomicLevel.Level(), err)
+	}
+}
+
+func TestZeroLoggerLevels(t *testing.T) {
+	// Such as the loggers built by the callers and passed by value to db and mongodb
+	loggers := []interface {
+		LevelHandler() http.Handler
+		WatchLevelSignals() func()
+		SetLevel(level string) error
+	}{
+		&Logger{SugaredLogger: zap.NewNop().Sugar()},
+		&OriginLogger{Logger: zap.NewNop()},
+	}
+	for _, logger := range loggers {
+		if status, p := serveLevel(t, logger.LevelHandler(), http.MethodGet, ""); status != http.StatusNotImplemented || p.Error == "" {
+			t.Errorf("%T: got %d %+v, want 501", logger, status, p)
+		}
+		logger.WatchLevelSignals()()
+		if err := logger.SetLevel("debug"); err != errNoLevel {
+			t.Errorf("%T: got error %v, want errNoLevel", logger, err)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		want    zapcore.Level
+		wantErr bool
+	}{
+		{"debug", zapcore.DebugLevel, false},
+		{" INFO ", zapcore.InfoLevel, false},
+		{"warning", zapcore.WarnLevel, false},
+		{"warn", zapcore.WarnLevel, false},
+		{"fatal", zapcore.FatalLevel, false},
+		{"verbose", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseLevel(tt.level)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("%q: got %s and error %v, want %s", tt.level, got, err, tt.want)
+		}
+	}
+}
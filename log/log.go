@@ -1,6 +1,12 @@
 package log
 
 import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -8,89 +14,266 @@ import (
 // Logger represents the struct of logger
 type Logger struct {
 	*zap.SugaredLogger
+	// Level is the level when the logger is created, use AtomicLevel to get or change the current level
 	Level zapcore.Level
+	// AtomicLevel is the level of the root logger, it is shared by the loggers created from the same NewLogger
+	AtomicLevel zap.AtomicLevel
+	levels      *levelRegistry
+	async       *asyncWriter
 }
 
 // NewLogger create an instance of zap SugarLogger with custom config
 func NewLogger(opts ...*Option) *Logger {
-	cfg := unifyConfig(opts...)
+	log, levels, async := buildLogger(opts...)
+	return &Logger{
+		SugaredLogger: log.Sugar(),
+		Level:         levels.root.Level(),
+		AtomicLevel:   levels.root,
+		levels:        levels,
+		async:         async,
+	}
+}
 
-	log, err := cfg.Build()
+// NewLoggerWithCore create an instance of zap SugarLogger which writes to the core, such as a zap observer
+// The level is checked before the core, so the core should enable all levels.
+func NewLoggerWithCore(core zapcore.Core, level zapcore.Level, opts ...zap.Option) *Logger {
+	levels := newLevelRegistry(zap.NewAtomicLevelAt(level))
+	log := zap.New(&levelCore{Core: core, enabler: levels.root}, opts...)
+	return &Logger{
+		SugaredLogger: log.Sugar(),
+		Level:         level,
+		AtomicLevel:   levels.root,
+		levels:        levels,
+	}
+}
+
+func buildLogger(opts ...*Option) (*zap.Logger, *levelRegistry, *asyncWriter) {
+	cfg, err := unifyConfig(opts...)
 	if err != nil {
 		panic(err)
 	}
-	return &Logger{
-		log.Sugar(),
-		cfg.Level.Level(),
+	redactor, err := unifyRedactor(opts...)
+	if err != nil {
+		panic(err)
+	}
+	sampling := unifySampling(opts...)
+	levels := newLevelRegistry(cfg.Level)
+	var errorOutput zapcore.WriteSyncer
+	if redactor != nil {
+		if errorOutput, _, err = zap.Open(cfg.ErrorOutputPaths...); err != nil {
+			panic(err)
+		}
+	}
+	var colorCore zapcore.Core
+	if colorPaths := splitColorPaths(cfg); len(colorPaths) > 0 {
+		if colorCore, err = newColorCore(cfg, colorPaths); err != nil {
+			panic(err)
+		}
 	}
+
+	// The level is checked by levelCore, so that the named loggers can have lower levels
+	cfg.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	// The sampling is set by samplingConfig, which supports the tick
+	cfg.Sampling = nil
+	log, err := cfg.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if colorCore != nil {
+			core = zapcore.NewTee(core, colorCore)
+		}
+		core = sampling.wrap(core, func(core zapcore.Core) zapcore.Core {
+			if redactor != nil {
+				core = &redactCore{Core: core, redactor: redactor, errorOutput: errorOutput}
+			}
+			return core
+		})
+		return &levelCore{Core: core, enabler: levels.root}
+	}))
+	if err != nil {
+		panic(err)
+	}
+	return log, levels, asyncWriterOf(cfg.OutputPaths)
+}
+
+// NamedLogger to get a child logger whose level can be changed independently
+// Its level follows the root logger until it is changed by the level handler.
+// Example:
+//
+// 		dbLogger := logger.NamedLogger("db")
+// 		// curl -X PUT -d '{"logger": "db", "level": "debug"}' http://localhost:8080/log/level
+//
+func (sl *Logger) NamedLogger(name string) *Logger {
+	logger := *sl
+	if sl.levels == nil {
+		logger.SugaredLogger = sl.Named(name)
+		return &logger
+	}
+	logger.SugaredLogger = sl.Desugar().WithOptions(sl.levels.wrapCore(name)).Named(name).Sugar()
+	return &logger
+}
+
+// LevelHandler to get the http handler to GET/PUT the levels of the logger and its named loggers
+// Example:
+//
+// 		http.Handle("/log/level", logger.LevelHandler())
+// 		// curl http://localhost:8080/log/level
+// 		// curl -X PUT -d '{"level": "debug"}' http://localhost:8080/log/level
+//
+// The handler responds 501 if the logger is not created by NewLogger or NewLoggerWithCore.
+func (sl *Logger) LevelHandler() http.Handler {
+	return levelHandler(sl.levels)
+}
+
+// WatchLevelSignals to switch the level to debug on SIGUSR1 and back on SIGUSR2, call stop to stop watching
+// It does nothing on windows, or if the logger is not created by NewLogger or NewLoggerWithCore.
+func (sl *Logger) WatchLevelSignals() (stop func()) {
+	return watchLevelSignals(sl.levels)
+}
+
+// SetLevel to change the level of the root logger, such as when the config is reloaded
+// The named loggers which have their own levels are not changed. An error is returned if the logger
+// has no AtomicLevel, such as a Logger{} wrapping a SugaredLogger.
+func (sl *Logger) SetLevel(level string) error {
+	return setLevel(sl.AtomicLevel, level)
 }
 
 // Log implements go-kit logger
+// The zap level is taken from the go-kit "level" key, "msg" is used as the message
+// and "caller" replaces the caller of zap, other keys are logged as fields.
 func (sl Logger) Log(kv ...interface{}) error {
-	level := sl.Level
-	forGokitLog(sl.SugaredLogger, level)
+	logGokit(sl.Desugar(), kv)
 	return nil
 }
 
 // FlushLogger encapsule Sync function
+// It writes all buffered logs if the logs are written asynchronously.
 func (sl *Logger) FlushLogger() {
 	if sl != nil {
 		_ = sl.Sync()
 	}
 }
 
+// Flush to write the buffered logs of all loggers, it should be called in the shutdown path of the
+// application, such as after SIGTERM is handled
+// The SDK does not handle SIGTERM itself, so that the signal handlers of the application are not affected.
+// Example:
+//
+// 		<-ctx.Done()
+// 		server.Shutdown(context.Background())
+// 		_ = log.Flush()
+//
+func Flush() error {
+	asyncErr := flushAsyncSinks()
+	// The remote sinks may be the outputs of the async writers, so they are flushed after them
+	if err := flushRemoteSinks(); err != nil {
+		return err
+	}
+	return asyncErr
+}
+
+// FlushOnSignal to write the buffered logs of all loggers when the process receives SIGTERM or SIGINT,
+// it stops watching the signals when ctx is done
+// The signal is raised again after the logs are written, so the process still exits by the signal. It is for the
+// applications which do not handle the signals, the others should call Flush in their shutdown path.
+// Example:
+//
+// 		log.FlushOnSignal(context.Background())
+//
+func FlushOnSignal(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		defer signal.Stop(ch)
+		select {
+		case sig := <-ch:
+			_ = Flush()
+			signal.Stop(ch)
+			raiseSignal(sig)
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// raiseSignal to send the signal to the process again, the process exits if it can not be sent
+var raiseSignal = func(sig os.Signal) {
+	if p, err := os.FindProcess(os.Getpid()); err == nil && p.Signal(sig) == nil {
+		return
+	}
+	os.Exit(1)
+}
+
+// DroppedLogs to get the amount of the logs dropped since the async buffer is full
+func (sl *Logger) DroppedLogs() uint64 {
+	if sl.async == nil {
+		return 0
+	}
+	return sl.async.Dropped()
+}
+
 // OriginLogger represents the struct of origin logger
 type OriginLogger struct {
 	*zap.Logger
+	// Level is the level when the logger is created, use AtomicLevel to get or change the current level
 	Level zapcore.Level
+	// AtomicLevel is the level of the root logger, it is shared by the loggers created from the same NewOriginLogger
+	AtomicLevel zap.AtomicLevel
+	levels      *levelRegistry
+	async       *asyncWriter
 }
 
 // NewOriginLogger create an instance of zap logger with custom config
 func NewOriginLogger(opts ...*Option) *OriginLogger {
-	cfg := unifyConfig(opts...)
-
-	log, err := cfg.Build()
-	if err != nil {
-		panic(err)
-	}
+	log, levels, async := buildLogger(opts...)
 	return &OriginLogger{
-		log,
-		cfg.Level.Level(),
+		Logger:      log,
+		Level:       levels.root.Level(),
+		AtomicLevel: levels.root,
+		levels:      levels,
+		async:       async,
 	}
 }
 
-// Log implements go-kit logger
+// NamedLogger to get a child logger whose level can be changed independently, see Logger.NamedLogger
+func (l *OriginLogger) NamedLogger(name string) *OriginLogger {
+	logger := *l
+	if l.levels == nil {
+		logger.Logger = l.Named(name)
+		return &logger
+	}
+	logger.Logger = l.WithOptions(l.levels.wrapCore(name)).Named(name)
+	return &logger
+}
+
+// LevelHandler to get the http handler to GET/PUT the levels, see Logger.LevelHandler
+func (l *OriginLogger) LevelHandler() http.Handler {
+	return levelHandler(l.levels)
+}
+
+// WatchLevelSignals to switch the level to debug on SIGUSR1 and back on SIGUSR2, see Logger.WatchLevelSignals
+func (l *OriginLogger) WatchLevelSignals() (stop func()) {
+	return watchLevelSignals(l.levels)
+}
+
+// SetLevel to change the level of the root logger, see Logger.SetLevel
+func (l *OriginLogger) SetLevel(level string) error {
+	return setLevel(l.AtomicLevel, level)
+}
+
+// Log implements go-kit logger, see Logger.Log
 func (l OriginLogger) Log(kv ...interface{}) error {
-	sugarLogger := l.Sugar()
-	level := l.Level
-	forGokitLog(sugarLogger, level)
+	logGokit(l.Logger, kv)
 	return nil
 }
 
-// FlushLogger encapsule Sync function
+// FlushLogger encapsule Sync function, see Logger.FlushLogger
 func (l *OriginLogger) FlushLogger() {
 	if l != nil {
 		_ = l.Sync()
 	}
 }
 
-func forGokitLog(sugarLogger *zap.SugaredLogger, level zapcore.Level, kv ...interface{}) {
-	switch level {
-	case zapcore.DebugLevel:
-		sugarLogger.Debugw("", kv...)
-	case zapcore.InfoLevel:
-		sugarLogger.Infow("", kv...)
-	case zapcore.WarnLevel:
-		sugarLogger.Warnw("", kv...)
-	case zapcore.ErrorLevel:
-		sugarLogger.Errorw("", kv...)
-	case zapcore.DPanicLevel:
-		sugarLogger.DPanicw("", kv...)
-	case zapcore.PanicLevel:
-		sugarLogger.Panicw("", kv...)
-	case zapcore.FatalLevel:
-		sugarLogger.Fatalw("", kv...)
-	default:
-		sugarLogger.Infow("", kv...)
+// DroppedLogs to get the amount of the logs dropped since the async buffer is full
+func (l *OriginLogger) DroppedLogs() uint64 {
+	if l.async == nil {
+		return 0
 	}
+	return l.async.Dropped()
 }
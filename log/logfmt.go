@@ -0,0 +1,190 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+func init() {
+	if err := zap.RegisterEncoder("logfmt", newLogfmtEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// logfmtEncoder encodes the entries as key=value pairs, such as:
+//
+// 		time="2006-01-02 15:04:05.000" level=info caller=main.go:12 msg=hello foo=bar
+//
+// The fields are sorted by key, and the objects and arrays are encoded as JSON.
+type logfmtEncoder struct {
+	*zapcore.MapObjectEncoder
+	cfg zapcore.EncoderConfig
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return &logfmtEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		cfg:              cfg,
+	}, nil
+}
+
+// Clone implements zapcore.Encoder
+func (enc *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := &logfmtEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		cfg:              enc.cfg,
+	}
+	for k, v := range enc.Fields {
+		clone.Fields[k] = v
+	}
+	return clone
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line := logfmtPool.Get()
+
+	if enc.cfg.TimeKey != "" && enc.cfg.EncodeTime != nil {
+		enc.appendPair(line, enc.cfg.TimeKey, enc.encodeTime(ent.Time))
+	}
+	if enc.cfg.LevelKey != "" && enc.cfg.EncodeLevel != nil {
+		enc.appendPair(line, enc.cfg.LevelKey, primitive(func(arr zapcore.ArrayEncoder) {
+			enc.cfg.EncodeLevel(ent.Level, arr)
+		}))
+	}
+	if enc.cfg.NameKey != "" && ent.LoggerName != "" {
+		encodeName := enc.cfg.EncodeName
+		if encodeName == nil {
+			encodeName = zapcore.FullNameEncoder
+		}
+		enc.appendPair(line, enc.cfg.NameKey, primitive(func(arr zapcore.ArrayEncoder) {
+			encodeName(ent.LoggerName, arr)
+		}))
+	}
+	if enc.cfg.CallerKey != "" && ent.Caller.Defined && enc.cfg.EncodeCaller != nil {
+		enc.appendPair(line, enc.cfg.CallerKey, primitive(func(arr zapcore.ArrayEncoder) {
+			enc.cfg.EncodeCaller(ent.Caller, arr)
+		}))
+	}
+	if enc.cfg.MessageKey != "" {
+		enc.appendPair(line, enc.cfg.MessageKey, ent.Message)
+	}
+
+	all := enc.Clone().(*logfmtEncoder)
+	for _, f := range fields {
+		f.AddTo(all)
+	}
+	keys := make([]string, 0, len(all.Fields))
+	for k := range all.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		enc.appendPair(line, k, all.Fields[k])
+	}
+
+	if enc.cfg.StacktraceKey != "" && ent.Stack != "" {
+		enc.appendPair(line, enc.cfg.StacktraceKey, ent.Stack)
+	}
+	if enc.cfg.LineEnding != "" {
+		line.AppendString(enc.cfg.LineEnding)
+	} else {
+		line.AppendString(zapcore.DefaultLineEnding)
+	}
+	return line, nil
+}
+
+func (enc *logfmtEncoder) appendPair(line *buffer.Buffer, key string, value interface{}) {
+	if line.Len() > 0 {
+		line.AppendByte(' ')
+	}
+	line.AppendString(logfmtKey(key))
+	line.AppendByte('=')
+	line.AppendString(logfmtValue(enc.format(value)))
+}
+
+func (enc *logfmtEncoder) format(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64, complex64, complex128:
+		return fmt.Sprint(v)
+	case time.Time:
+		if enc.cfg.EncodeTime != nil {
+			return fmt.Sprint(enc.encodeTime(v))
+		}
+		return v.String()
+	case time.Duration:
+		if enc.cfg.EncodeDuration != nil {
+			return fmt.Sprint(primitive(func(arr zapcore.ArrayEncoder) {
+				enc.cfg.EncodeDuration(v, arr)
+			}))
+		}
+		return v.String()
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+func (enc *logfmtEncoder) encodeTime(t time.Time) interface{} {
+	return primitive(func(arr zapcore.ArrayEncoder) {
+		enc.cfg.EncodeTime(t, arr)
+	})
+}
+
+// primitive to get the value appended by the zap primitive encoders, such as EncodeTime
+func primitive(encode func(zapcore.ArrayEncoder)) interface{} {
+	m := zapcore.NewMapObjectEncoder()
+	_ = m.AddArray("v", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		encode(arr)
+		return nil
+	}))
+	if values, ok := m.Fields["v"].([]interface{}); ok && len(values) > 0 {
+		return values[0]
+	}
+	return nil
+}
+
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func logfmtValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r) {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
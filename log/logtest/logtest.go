@@ -0,0 +1,185 @@
+// Package logtest records the logs in memory for tests
+package logtest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/uhhc/sdk-common-go/log"
+)
+
+// TestingT is the subset of testing.TB used by the assertions
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Recorder keeps the entries written by the logger
+type Recorder struct {
+	t    TestingT
+	logs *observer.ObservedLogs
+}
+
+// Entries are the recorded entries, the fields of log.Logger.With are included in their Context
+type Entries []observer.LoggedEntry
+
+// New to get a logger at debug level which records the entries in memory, and the recorder of them
+// The logger can be passed to the clients, such as db.NewDB(*logger).
+// Example:
+//
+// 		logger, logs := logtest.New(t)
+// 		client, err := mongodb.NewMongoClient(*logger)
+// 		...
+// 		logs.AssertLogged(zap.ErrorLevel, "connect to mongodb error", "host", "localhost")
+//
+func New(t TestingT) (*log.Logger, *Recorder) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := log.NewLoggerWithCore(core, zapcore.DebugLevel, zap.AddCaller())
+	return logger, &Recorder{t: t, logs: logs}
+}
+
+// All to get all recorded entries
+func (r *Recorder) All() Entries {
+	return r.logs.All()
+}
+
+// Len to get the amount of the recorded entries
+func (r *Recorder) Len() int {
+	return r.logs.Len()
+}
+
+// Reset to drop the recorded entries
+func (r *Recorder) Reset() {
+	r.logs.TakeAll()
+}
+
+// FilterLevel to get the entries at the level
+func (r *Recorder) FilterLevel(level zapcore.Level) Entries {
+	return r.All().FilterLevel(level)
+}
+
+// FilterMessage to get the entries with the message
+func (r *Recorder) FilterMessage(msg string) Entries {
+	return r.All().FilterMessage(msg)
+}
+
+// FilterKey to get the entries having the field key
+func (r *Recorder) FilterKey(key string) Entries {
+	return r.All().FilterKey(key)
+}
+
+// FilterField to get the entries having the field key with the value
+func (r *Recorder) FilterField(key string, value interface{}) Entries {
+	return r.All().FilterField(key, value)
+}
+
+// AssertLogged to assert that an entry at the level with the message and fields is logged
+// The fields are key/value pairs like the ones of Infow, the entry may have other fields.
+func (r *Recorder) AssertLogged(level zapcore.Level, msg string, fields ...interface{}) bool {
+	r.t.Helper()
+	entries := r.All().FilterLevel(level).FilterMessage(msg)
+	for i := 0; i+1 < len(fields); i += 2 {
+		entries = entries.FilterField(fmt.Sprint(fields[i]), fields[i+1])
+	}
+	if len(entries) > 0 {
+		return true
+	}
+	r.t.Errorf("logtest: no %s entry %q with fields %v is logged, the entries are:\n%s", level, msg, fields, r.All())
+	return false
+}
+
+// AssertNotLogged to assert that no entry at the level with the message is logged
+func (r *Recorder) AssertNotLogged(level zapcore.Level, msg string) bool {
+	r.t.Helper()
+	entries := r.All().FilterLevel(level).FilterMessage(msg)
+	if len(entries) == 0 {
+		return true
+	}
+	r.t.Errorf("logtest: %s entry %q is logged unexpectedly:\n%s", level, msg, entries)
+	return false
+}
+
+// FilterLevel to get the entries at the level
+func (es Entries) FilterLevel(level zapcore.Level) Entries {
+	return es.filter(func(e observer.LoggedEntry) bool {
+		return e.Level == level
+	})
+}
+
+// FilterMessage to get the entries with the message
+func (es Entries) FilterMessage(msg string) Entries {
+	return es.filter(func(e observer.LoggedEntry) bool {
+		return e.Message == msg
+	})
+}
+
+// FilterKey to get the entries having the field key
+func (es Entries) FilterKey(key string) Entries {
+	return es.filter(func(e observer.LoggedEntry) bool {
+		_, ok := e.ContextMap()[key]
+		return ok
+	})
+}
+
+// FilterField to get the entries having the field key with the value
+// The numbers are compared by value, so 1 matches int64(1), and the errors are compared by their messages.
+func (es Entries) FilterField(key string, value interface{}) Entries {
+	return es.filter(func(e observer.LoggedEntry) bool {
+		v, ok := e.ContextMap()[key]
+		return ok && equalValue(value, v)
+	})
+}
+
+// String implements fmt.Stringer, one entry per line
+func (es Entries) String() string {
+	var b strings.Builder
+	for _, e := range es {
+		fmt.Fprintf(&b, "\t%s %q %v\n", e.Level, e.Message, e.ContextMap())
+	}
+	return b.String()
+}
+
+func (es Entries) filter(match func(observer.LoggedEntry) bool) Entries {
+	var filtered Entries
+	for _, e := range es {
+		if match(e) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+func equalValue(expected, actual interface{}) bool {
+	if reflect.DeepEqual(expected, actual) {
+		return true
+	}
+	if x, ok := toFloat(expected); ok {
+		y, ok := toFloat(actual)
+		return ok && x == y
+	}
+	switch v := expected.(type) {
+	case error:
+		return v.Error() == fmt.Sprint(actual)
+	case fmt.Stringer:
+		return v.String() == fmt.Sprint(actual)
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,60 @@
+package logtest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeT records the failures of the assertions
+type fakeT struct {
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	logger, logs := New(t)
+	logger.With("request_id", "abc").Errorw("query error", "error", errors.New("timeout"), "retries", 3)
+	logger.NamedLogger("db").Infow("connected", "host", "localhost")
+
+	logs.AssertLogged(zap.ErrorLevel, "query error", "request_id", "abc", "error", errors.New("timeout"), "retries", 3)
+	logs.AssertLogged(zap.InfoLevel, "connected", "host", "localhost")
+	logs.AssertNotLogged(zap.WarnLevel, "query error")
+	if n := len(logs.FilterKey("request_id")); n != 1 {
+		t.Errorf("got %d entries with request_id, want 1", n)
+	}
+
+	if err := logger.SetLevel("info"); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debugw("filtered")
+	logs.AssertNotLogged(zap.DebugLevel, "filtered")
+
+	logs.Reset()
+	if logs.Len() != 0 {
+		t.Errorf("got %d entries after Reset, want 0", logs.Len())
+	}
+}
+
+func TestRecorderFailures(t *testing.T) {
+	ft := &fakeT{}
+	logger, logs := New(ft)
+	logger.Warnw("slow query", "elapsed", "2s")
+
+	if logs.AssertLogged(zap.WarnLevel, "slow query", "elapsed", "1s") {
+		t.Error("got AssertLogged true for a different field value")
+	}
+	if logs.AssertNotLogged(zap.WarnLevel, "slow query") {
+		t.Error("got AssertNotLogged true for a logged entry")
+	}
+	if len(ft.errors) != 2 {
+		t.Errorf("got failures %q, want 2", ft.errors)
+	}
+}
@@ -1,19 +1,93 @@
 package log
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 // Option represents the option of log instance
+// The config tags are the keys of the config package, such as "log.level" for the section "log".
 type Option struct {
-	DisableStacktrace *bool  `json:"disableStacktrace"`
-	LogLevel          string `json:"logLevel"`
-	LogFile           string `json:"logFile"`
+	DisableStacktrace *bool  `json:"disableStacktrace" config:"disable_stacktrace"`
+	LogLevel          string `json:"logLevel" config:"level"`
+	LogFile           string `json:"logFile" config:"file"`
+	// Rotation of LogFile, it is enabled if RotateMaxSize or RotateInterval is set
+	RotateMaxSize    int   `json:"rotateMaxSize" config:"rotate_max_size"`       // megabytes
+	RotateInterval   int64 `json:"rotateInterval" config:"rotate_interval"`      // seconds, aligned to the local midnight
+	RotateMaxBackups int   `json:"rotateMaxBackups" config:"rotate_max_backups"` // 0 means keeping all backups
+	RotateMaxAge     int   `json:"rotateMaxAge" config:"rotate_max_age"`         // days, 0 means keeping all backups
+	RotateCompress   *bool `json:"rotateCompress" config:"rotate_compress"`      // gzip the backups
+	// Encoding is one of json, console and logfmt, json by default
+	Encoding string `json:"encoding" config:"encoding"`
+	// TimeFormat is the layout of time.Format, "2006-01-02 15:04:05.000" by default
+	TimeFormat string `json:"timeFormat" config:"time_format"`
+	// TimeZone is the IANA name such as "UTC" and "Asia/Shanghai", the local time zone by default
+	TimeZone string `json:"timeZone" config:"time_zone"`
+	// OutputPaths are the zap sink URLs or files which the logs are written to, stdout by default
+	// LogFile is appended to them.
+	OutputPaths []string `json:"outputPaths" config:"output_paths"`
+	// ErrorOutputPaths are the zap sink URLs or files which the internal errors of the logger are written to, stderr by default
+	ErrorOutputPaths []string `json:"errorOutputPaths" config:"error_output_paths"`
+	// Key names of the entries, "-" omits the key
+	MessageKey    string `json:"messageKey" config:"message_key"`
+	LevelKey      string `json:"levelKey" config:"level_key"`
+	TimeKey       string `json:"timeKey" config:"time_key"`
+	NameKey       string `json:"nameKey" config:"name_key"`
+	CallerKey     string `json:"callerKey" config:"caller_key"`
+	StacktraceKey string `json:"stacktraceKey" config:"stacktrace_key"`
+	// RedactKeys are masked besides DefaultRedactKeys
+	RedactKeys []string `json:"redactKeys" config:"redact_keys"`
+	// RedactPatterns are the regular expressions of the values to mask besides the credentials in URIs,
+	// card numbers and emails
+	RedactPatterns   []string `json:"redactPatterns" config:"redact_patterns"`
+	DisableRedaction *bool    `json:"disableRedaction" config:"disable_redaction"`
+	// Sampling logs the first SamplingInitial entries with the same level and message in every
+	// SamplingTick, and every SamplingThereafter-th entry after that
+	SamplingInitial    int   `json:"samplingInitial" config:"sampling_initial"`
+	SamplingThereafter int   `json:"samplingThereafter" config:"sampling_thereafter"`
+	SamplingTick       int64 `json:"samplingTick" config:"sampling_tick"` // seconds
+	DisableSampling    *bool `json:"disableSampling" config:"disable_sampling"`
+	// DedupWindow suppresses the same messages in the window and logs the count of them, 0 means no deduplication
+	DedupWindow int64 `json:"dedupWindow" config:"dedup_window"` // seconds
+	// Async writes the logs in background, FlushLogger writes all buffered logs
+	Async              *bool  `json:"async" config:"async"`
+	AsyncBufferSize    int    `json:"asyncBufferSize" config:"async_buffer_size"`       // bytes
+	AsyncFlushInterval int64  `json:"asyncFlushInterval" config:"async_flush_interval"` // milliseconds
+	AsyncOverflow      string `json:"asyncOverflow" config:"async_overflow"`            // OverflowBlock or OverflowDrop when the buffer is full
+	// SyslogURL sends the logs to a syslog server, such as syslog+udp://127.0.0.1:514?app=demo&facility=local0,
+	// the schemes are syslog+udp, syslog+tcp and syslog+tls
+	SyslogURL string `json:"syslogURL" config:"syslog_url"`
+	// HTTPSinkURL posts the logs in batches to a collector, such as httplog+https://collector.example.com/v1/logs
+	HTTPSinkURL string `json:"httpSinkURL" config:"http_sink_url"`
+}
+
+// Validate to check the level, encoding and async overflow, the config package calls it after loading
+func (o *Option) Validate() error {
+	if o.LogLevel != "" {
+		if _, err := parseLevel(o.LogLevel); err != nil {
+			return fmt.Errorf("log: %w", err)
+		}
+	}
+	switch o.Encoding {
+	case "", "json", "console", "logfmt":
+	default:
+		return fmt.Errorf("log: invalid encoding %q, it should be json, console or logfmt", o.Encoding)
+	}
+	switch o.AsyncOverflow {
+	case "", OverflowBlock, OverflowDrop:
+	default:
+		return fmt.Errorf("log: invalid async overflow %q, it should be %s or %s", o.AsyncOverflow, OverflowBlock, OverflowDrop)
+	}
+	return nil
 }
 
-func unifyConfig(opts ...*Option) *zap.Config {
+func unifyConfig(opts ...*Option) (*zap.Config, error) {
 	var (
 		level, file       string
 		disableStacktrace bool
@@ -47,40 +121,336 @@ func unifyConfig(opts ...*Option) *zap.Config {
 		}
 	}
 
+	// Get log file rotation
+	rotate := unifyRotateConfig(opts...)
+
 	// Set DisableStacktrace
 	cfg.DisableStacktrace = disableStacktrace
 	// Set log level
-	cfg.Level.SetLevel(convertLogLevel(level))
+	// The level is debug if it is not set or invalid
+	lvl, err := parseLevel(level)
+	if level == "" || err != nil {
+		lvl = zapcore.DebugLevel
+	}
+	cfg.Level.SetLevel(lvl)
 	// Set output path
 	var paths = []string{
 		"stdout",
 	}
+	if opts != nil && len(opts[0].OutputPaths) > 0 {
+		paths = append([]string{}, opts[0].OutputPaths...)
+	} else if v := getStringList("LOG_OUTPUT_PATHS"); len(v) > 0 {
+		paths = v
+	}
 	if file != "" {
+		if rotate.enabled() {
+			file = registerRotateSink(file, rotate)
+		}
 		paths = append(paths, file)
 	}
+	// Set async writing
+	if async, err := unifyAsync(opts...); err != nil {
+		return nil, err
+	} else if async != nil {
+		url, err := registerAsyncSink(paths, *async)
+		if err != nil {
+			return nil, err
+		}
+		paths = []string{url}
+	}
 	cfg.OutputPaths = paths
+	// Set error output path
+	if opts != nil && len(opts[0].ErrorOutputPaths) > 0 {
+		cfg.ErrorOutputPaths = opts[0].ErrorOutputPaths
+	} else if v := getStringList("LOG_ERROR_OUTPUT_PATHS"); len(v) > 0 {
+		cfg.ErrorOutputPaths = v
+	}
 
 	// Set encoder
-	cfg.EncoderConfig.TimeKey = "time"
-	cfg.EncoderConfig.EncodeTime = timeEncoder
+	if err := unifyEncoder(&cfg, opts...); err != nil {
+		return nil, err
+	}
+	// Set remote sinks, they are buffered by themselves
+	remotes, err := unifyRemoteSinks(cfg.EncoderConfig.LevelKey, opts...)
+	if err != nil {
+		return nil, err
+	}
+	cfg.OutputPaths = append(cfg.OutputPaths, remotes...)
+
+	return &cfg, nil
+}
+
+func unifyEncoder(cfg *zap.Config, opts ...*Option) error {
+	var opt Option
+	if opts != nil && opts[0] != nil {
+		opt = *opts[0]
+	}
+
+	encoding := optionString(opt.Encoding, "LOG_ENCODING")
+	switch encoding {
+	case "", "json":
+		cfg.Encoding = "json"
+	case "console":
+		// The colored levels are only written to stdout and stderr, see splitColorPaths
+		cfg.Encoding = "console"
+		cfg.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	case "logfmt":
+		cfg.Encoding = "logfmt"
+	default:
+		return fmt.Errorf("log: invalid encoding %q, it should be json, console or logfmt", encoding)
+	}
+
+	layout := optionString(opt.TimeFormat, "LOG_TIME_FORMAT")
+	if layout == "" {
+		layout = defaultTimeFormat
+	}
+	loc := time.Local
+	if tz := optionString(opt.TimeZone, "LOG_TIME_ZONE"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return fmt.Errorf("log: invalid time zone %q: %w", tz, err)
+		}
+	}
+	cfg.EncoderConfig.EncodeTime = newTimeEncoder(layout, loc)
 	cfg.EncoderConfig.EncodeDuration = milliSecondsDurationEncoder
 
-	return &cfg
+	keys := []struct {
+		key   *string
+		value string
+		env   string
+		def   string
+	}{
+		{&cfg.EncoderConfig.MessageKey, opt.MessageKey, "LOG_MESSAGE_KEY", "msg"},
+		{&cfg.EncoderConfig.LevelKey, opt.LevelKey, "LOG_LEVEL_KEY", "level"},
+		{&cfg.EncoderConfig.TimeKey, opt.TimeKey, "LOG_TIME_KEY", "time"},
+		{&cfg.EncoderConfig.NameKey, opt.NameKey, "LOG_NAME_KEY", "logger"},
+		{&cfg.EncoderConfig.CallerKey, opt.CallerKey, "LOG_CALLER_KEY", "caller"},
+		{&cfg.EncoderConfig.StacktraceKey, opt.StacktraceKey, "LOG_STACKTRACE_KEY", "stacktrace"},
+	}
+	for _, k := range keys {
+		v := optionString(k.value, k.env)
+		switch v {
+		case "":
+			*k.key = k.def
+		case "-":
+			*k.key = ""
+		default:
+			*k.key = v
+		}
+	}
+	return nil
+}
+
+// splitColorPaths to remove stdout and stderr from the output paths of the console encoding, and get
+// them to be written with the colored levels, so that the log files do not contain the color codes
+// The asynchronous outputs are not split, since they are written by one writer.
+func splitColorPaths(cfg *zap.Config) []string {
+	if cfg.Encoding != "console" {
+		return nil
+	}
+	var colorPaths, paths []string
+	for _, path := range cfg.OutputPaths {
+		if path == "stdout" || path == "stderr" {
+			colorPaths = append(colorPaths, path)
+		} else {
+			paths = append(paths, path)
+		}
+	}
+	cfg.OutputPaths = paths
+	return colorPaths
+}
+
+// newColorCore to get the core writing to the paths with the colored levels
+func newColorCore(cfg *zap.Config, paths []string) (zapcore.Core, error) {
+	sink, _, err := zap.Open(paths...)
+	if err != nil {
+		return nil, err
+	}
+	encoderConfig := cfg.EncoderConfig
+	encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	// The level is checked by levelCore
+	return zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), sink, zapcore.DebugLevel), nil
+}
+
+func unifyRedactor(opts ...*Option) (*redactor, error) {
+	var opt Option
+	if opts != nil && opts[0] != nil {
+		opt = *opts[0]
+	}
+
+	disable := viper.GetBool("LOG_DISABLE_REDACTION")
+	if opt.DisableRedaction != nil {
+		disable = *opt.DisableRedaction
+	}
+	if disable {
+		return nil, nil
+	}
+	keys := opt.RedactKeys
+	if len(keys) == 0 {
+		keys = getStringList("LOG_REDACT_KEYS")
+	}
+	return newRedactor(keys, opt.RedactPatterns)
 }
 
-func convertLogLevel(level string) zapcore.Level {
-	m := map[string]zapcore.Level{
-		"debug":   zap.DebugLevel,
-		"info":    zap.InfoLevel,
-		"warning": zap.WarnLevel,
-		"error":   zap.ErrorLevel,
-		"dpanic":  zap.DPanicLevel,
-		"panic":   zap.PanicLevel,
-		"fatal":   zap.FatalLevel,
-	}
-	if v, ok := m[level]; ok {
-		return v
-	}
-	// If level string is invalid, return debug level for default
-	return zap.DebugLevel
+func unifyRemoteSinks(levelKey string, opts ...*Option) ([]string, error) {
+	var opt Option
+	if opts != nil && opts[0] != nil {
+		opt = *opts[0]
+	}
+
+	var paths []string
+	if syslog := optionString(opt.SyslogURL, "LOG_SYSLOG_URL"); syslog != "" {
+		u, err := url.Parse(syslog)
+		if err != nil {
+			return nil, fmt.Errorf("log: invalid syslog URL: %w", err)
+		}
+		switch u.Scheme {
+		case SyslogUDPScheme, SyslogTCPScheme, SyslogTLSScheme:
+		default:
+			return nil, fmt.Errorf("log: invalid syslog scheme %q", u.Scheme)
+		}
+		// The sink needs the level key to get the severity
+		q := u.Query()
+		if q.Get("levelKey") == "" && levelKey != "" {
+			q.Set("levelKey", levelKey)
+			u.RawQuery = q.Encode()
+		}
+		paths = append(paths, u.String())
+	}
+	if httpSink := optionString(opt.HTTPSinkURL, "LOG_HTTP_SINK_URL"); httpSink != "" {
+		if !strings.HasPrefix(httpSink, HTTPLogScheme+"://") && !strings.HasPrefix(httpSink, HTTPSLogScheme+"://") {
+			return nil, fmt.Errorf("log: invalid HTTP sink URL, the scheme should be %s or %s", HTTPLogScheme, HTTPSLogScheme)
+		}
+		paths = append(paths, httpSink)
+	}
+	return paths, nil
+}
+
+func unifyAsync(opts ...*Option) (*asyncConfig, error) {
+	var opt Option
+	if opts != nil && opts[0] != nil {
+		opt = *opts[0]
+	}
+
+	enabled := viper.GetBool("LOG_ASYNC")
+	if opt.Async != nil {
+		enabled = *opt.Async
+	}
+	if !enabled {
+		return nil, nil
+	}
+	cfg := &asyncConfig{
+		bufferSize:    opt.AsyncBufferSize,
+		flushInterval: time.Duration(opt.AsyncFlushInterval) * time.Millisecond,
+	}
+	if cfg.bufferSize <= 0 {
+		cfg.bufferSize = viper.GetInt("LOG_ASYNC_BUFFER_SIZE")
+	}
+	if cfg.bufferSize <= 0 {
+		cfg.bufferSize = DefaultAsyncBufferSize
+	}
+	if cfg.flushInterval <= 0 {
+		cfg.flushInterval = time.Duration(viper.GetInt64("LOG_ASYNC_FLUSH_INTERVAL")) * time.Millisecond
+	}
+	if cfg.flushInterval <= 0 {
+		cfg.flushInterval = DefaultAsyncFlushInterval
+	}
+	switch overflow := optionString(opt.AsyncOverflow, "LOG_ASYNC_OVERFLOW"); overflow {
+	case "", OverflowBlock:
+	case OverflowDrop:
+		cfg.drop = true
+	default:
+		return nil, fmt.Errorf("log: invalid async overflow %q, it should be %s or %s", overflow, OverflowBlock, OverflowDrop)
+	}
+	return cfg, nil
+}
+
+func unifySampling(opts ...*Option) samplingConfig {
+	var opt Option
+	if opts != nil && opts[0] != nil {
+		opt = *opts[0]
+	}
+
+	cfg := samplingConfig{
+		disabled:    viper.GetBool("LOG_DISABLE_SAMPLING"),
+		initial:     opt.SamplingInitial,
+		thereafter:  opt.SamplingThereafter,
+		tick:        time.Duration(opt.SamplingTick) * time.Second,
+		dedupWindow: time.Duration(opt.DedupWindow) * time.Second,
+	}
+	if opt.DisableSampling != nil {
+		cfg.disabled = *opt.DisableSampling
+	}
+	if cfg.initial <= 0 {
+		cfg.initial = viper.GetInt("LOG_SAMPLING_INITIAL")
+	}
+	if cfg.initial <= 0 {
+		cfg.initial = DefaultSamplingInitial
+	}
+	if cfg.thereafter <= 0 {
+		cfg.thereafter = viper.GetInt("LOG_SAMPLING_THEREAFTER")
+	}
+	if cfg.thereafter <= 0 {
+		cfg.thereafter = DefaultSamplingThereafter
+	}
+	if cfg.tick <= 0 {
+		cfg.tick = time.Duration(viper.GetInt64("LOG_SAMPLING_TICK")) * time.Second
+	}
+	if cfg.tick <= 0 {
+		cfg.tick = DefaultSamplingTick
+	}
+	if cfg.dedupWindow <= 0 {
+		cfg.dedupWindow = time.Duration(viper.GetInt64("LOG_DEDUP_WINDOW")) * time.Second
+	}
+	return cfg
+}
+
+// optionString to get the option value, or the viper value if it is empty
+func optionString(value, key string) string {
+	if value != "" {
+		return value
+	}
+	return viper.GetString(key)
+}
+
+// getStringList to get the comma separated viper value
+func getStringList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(viper.GetString(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func unifyRotateConfig(opts ...*Option) rotateConfig {
+	var opt Option
+	if opts != nil && opts[0] != nil {
+		opt = *opts[0]
+	}
+	cfg := rotateConfig{
+		maxSize:    int64(opt.RotateMaxSize) * 1024 * 1024,
+		interval:   time.Duration(opt.RotateInterval) * time.Second,
+		maxBackups: opt.RotateMaxBackups,
+		maxAge:     time.Duration(opt.RotateMaxAge) * 24 * time.Hour,
+	}
+	if cfg.maxSize == 0 {
+		cfg.maxSize = viper.GetInt64("LOG_ROTATE_MAX_SIZE") * 1024 * 1024
+	}
+	if cfg.interval == 0 {
+		cfg.interval = time.Duration(viper.GetInt64("LOG_ROTATE_INTERVAL")) * time.Second
+	}
+	if cfg.maxBackups == 0 {
+		cfg.maxBackups = viper.GetInt("LOG_ROTATE_MAX_BACKUPS")
+	}
+	if cfg.maxAge == 0 {
+		cfg.maxAge = time.Duration(viper.GetInt64("LOG_ROTATE_MAX_AGE")) * 24 * time.Hour
+	}
+	if opt.RotateCompress != nil {
+		cfg.compress = *opt.RotateCompress
+	} else {
+		cfg.compress = viper.GetBool("LOG_ROTATE_COMPRESS")
+	}
+	return cfg
 }
@@ -0,0 +1,96 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestOptionLogLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		want  zapcore.Level
+	}{
+		{"", zapcore.DebugLevel},
+		{"info", zapcore.InfoLevel},
+		{"warn", zapcore.WarnLevel},
+		{"warning", zapcore.WarnLevel},
+		{"ERROR", zapcore.ErrorLevel},
+		{"unknown", zapcore.DebugLevel},
+	}
+	for _, tt := range tests {
+		logger := NewLogger(&Option{LogLevel: tt.level, OutputPaths: []string{os.DevNull}})
+		if got := logger.AtomicLevel.Level(); got != tt.want {
+			t.Errorf("level %q: got %s, want %s", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestConsoleColorsOnlyOnTerminalOutputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "console.log")
+	logger := NewLogger(&Option{
+		LogLevel:    "info",
+		Encoding:    "console",
+		LogFile:     file,
+		OutputPaths: []string{os.DevNull},
+	})
+	logger.Warnw("plain level")
+	_ = logger.Sync()
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "WARN") || strings.Contains(string(b), "\x1b[") {
+		t.Errorf("got %q, want the level without color codes", b)
+	}
+}
+
+func TestSplitColorPaths(t *testing.T) {
+	cfg := &zap.Config{Encoding: "console", OutputPaths: []string{"stdout", "/var/log/app.log", "stderr"}}
+	colorPaths := splitColorPaths(cfg)
+	if strings.Join(colorPaths, ",") != "stdout,stderr" || strings.Join(cfg.OutputPaths, ",") != "/var/log/app.log" {
+		t.Errorf("got color paths %v and paths %v, want stdout and stderr split", colorPaths, cfg.OutputPaths)
+	}
+
+	cfg = &zap.Config{Encoding: "json", OutputPaths: []string{"stdout"}}
+	if colorPaths := splitColorPaths(cfg); len(colorPaths) != 0 || len(cfg.OutputPaths) != 1 {
+		t.Errorf("got color paths %v, want none for json", colorPaths)
+	}
+}
+
+func TestColorCore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "color.log")
+
+	cfg := zap.NewProductionConfig()
+	cfg.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	core, err := newColorCore(&cfg, []string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zap.New(core).Warn("colored level")
+	_ = core.Sync()
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "\x1b[33mWARN\x1b[0m") {
+		t.Errorf("got %q, want the colored level", b)
+	}
+}
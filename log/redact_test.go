@@ -0,0 +1,192 @@
+package log
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactKeys(t *testing.T) {
+	r, err := newRedactor([]string{"api_key"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"password", true},
+		{"dbPassword", true},
+		{"DB_PASSWORD", true},
+		{"access_token", true},
+		{"X-Auth-Token", true},
+		{"HTTPToken", true},
+		{"Authorization", true},
+		{"clientSecret", true},
+		{"token2", true},
+		{"apiKey", true},
+		{"API_KEY", true},
+		{"author", false},
+		{"tokenizer", false},
+		{"passwordless_enabled", false},
+		{"secretary", false},
+		{"key", false},
+		{"api", false},
+		{"name", false},
+	}
+	for _, tt := range tests {
+		if got := r.sensitiveKey(tt.key); got != tt.want {
+			t.Errorf("key %q: got sensitive %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRedactString(t *testing.T) {
+	r, err := newRedactor(nil, []string{`\bID-\d{6}\b`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in   string
+		want string
+	}{
+		// Masked
+		{"connect mongodb://root:pass@db:27017", "connect mongodb://root:REDACTED@db:27017"},
+		{"paid by 4111 1111 1111 1111", "paid by REDACTED"},
+		{"paid by 4111-1111-1111-1111.", "paid by REDACTED."},
+		{"amex 3782-822463-10005", "amex REDACTED"},
+		{"card number: 4111111111111111", "card number: REDACTED"},
+		{`{"card_no":"4111111111111111"}`, `{"card_no":"REDACTED"}`},
+		{"pan=5500005555555559", "pan=REDACTED"},
+		{"sent to alice@example.com", "sent to REDACTED"},
+		{"user ID-123456", "user REDACTED"},
+		// Kept
+		{"mongodb://db:27017/test", "mongodb://db:27017/test"},
+		{"created at 1589353200123456789", "created at 1589353200123456789"},
+		{"order 1260211346187284480", "order 1260211346187284480"},
+		{"id 4111111111111111", "id 4111111111111111"},
+		{"paid by 4111 1111 1111 1112", "paid by 4111 1111 1111 1112"},
+		{"card number: 4111111111111112", "card number: 4111111111111112"},
+		{"call 1234 5678 9012", "call 1234 5678 9012"},
+		{"at 2020-05-13 08:30:00", "at 2020-05-13 08:30:00"},
+	}
+	for _, tt := range tests {
+		if got := r.redactString(tt.in); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := newRedactor(nil, []string{"("}); err == nil {
+		t.Error("got no error, want the invalid pattern")
+	}
+}
+
+type redactAccount struct {
+	Name     string
+	Password string `log:"redact"`
+	Pin      int    `log:"redact"`
+}
+
+func TestRedactFields(t *testing.T) {
+	r, err := newRedactor(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := []zapcore.Field{
+		zap.String("password", "p"),
+		zap.Int("token", 1),
+		zap.String("card_number", "4111111111111111"),
+		zap.String("order_id", "4111111111111111"),
+		zap.Int64("created_at", 1589353200123456789),
+		zap.Error(errors.New("login alice@example.com failed")),
+		zap.Reflect("account", &redactAccount{Name: "alice", Password: "p", Pin: 1234}),
+		zap.String("name", "alice"),
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range r.redactFields(fields) {
+		f.AddTo(enc)
+	}
+	want := map[string]interface{}{
+		"password":    RedactedValue,
+		"token":       RedactedValue,
+		"card_number": RedactedValue,
+		"order_id":    "4111111111111111",
+		"created_at":  int64(1589353200123456789),
+		"error":       "login REDACTED failed",
+		"name":        "alice",
+	}
+	for k, v := range want {
+		if enc.Fields[k] != v {
+			t.Errorf("got %s=%v, want %v", k, enc.Fields[k], v)
+		}
+	}
+	account, ok := enc.Fields["account"].(*redactAccount)
+	if !ok || account.Name != "alice" || account.Password != RedactedValue || account.Pin != 0 {
+		t.Errorf("got account %+v, want the tagged fields masked", enc.Fields["account"])
+	}
+	if fields[0].String != "p" {
+		t.Error("got the fields of the caller changed")
+	}
+}
+
+func TestRedactCore(t *testing.T) {
+	r, err := newRedactor(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infoCore, infoLogs := observer.New(zapcore.InfoLevel)
+	errorCore, errorLogs := observer.New(zapcore.ErrorLevel)
+	core := &redactCore{Core: zapcore.NewTee(infoCore, errorCore), redactor: r}
+	logger := zap.New(core).With(zap.String("token", "t"))
+
+	logger.Debug("dropped")
+	logger.Info("login alice@example.com", zap.String("password", "p"))
+	logger.Error("failed")
+
+	if infoLogs.Len() != 2 || errorLogs.Len() != 1 {
+		t.Fatalf("got %d info and %d error entries, want 2 and 1 by the levels of the cores", infoLogs.Len(), errorLogs.Len())
+	}
+	entry := infoLogs.All()[0]
+	if entry.Message != "login REDACTED" {
+		t.Errorf("got message %q", entry.Message)
+	}
+	fields := entry.ContextMap()
+	if fields["token"] != RedactedValue || fields["password"] != RedactedValue {
+		t.Errorf("got fields %v, want the token and password masked", fields)
+	}
+}
+
+func TestRedactCoreSampling(t *testing.T) {
+	r, err := newRedactor(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	observed, logs := observer.New(zapcore.DebugLevel)
+	core := &redactCore{Core: zapcore.NewSampler(observed, time.Minute, 2, 100), redactor: r}
+	logger := zap.New(core)
+	for i := 0; i < 5; i++ {
+		logger.Info("repeated")
+	}
+	if logs.Len() != 2 {
+		t.Errorf("got %d entries, want 2 by the sampler", logs.Len())
+	}
+}
+
+func TestRedactionOption(t *testing.T) {
+	disable := true
+	r, err := unifyRedactor(&Option{DisableRedaction: &disable})
+	if err != nil || r != nil {
+		t.Errorf("got redactor %v and error %v, want none when disabled", r, err)
+	}
+	r, err = unifyRedactor(&Option{RedactKeys: []string{"session_id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.sensitiveKey("sessionId") || !r.sensitiveKey("password") {
+		t.Error("got the extra or the default keys not matched")
+	}
+}
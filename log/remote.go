@@ -0,0 +1,296 @@
+package log
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/uhhc/sdk-common-go/util/backoff"
+)
+
+// Default settings of the remote sinks
+const (
+	DefaultRemoteBufferSize    = 10000
+	DefaultRemoteBatchSize     = 100
+	DefaultRemoteFlushInterval = time.Second
+	DefaultRemoteRetries       = 3
+	DefaultRemoteTimeout       = 5 * time.Second
+)
+
+// remoteSinks keeps one sink per URL, so that the loggers share the connections and buffers
+var remoteSinks = struct {
+	sync.Mutex
+	sinks map[string]*remoteSink
+}{sinks: map[string]*remoteSink{}}
+
+// registerRemoteSink to register the zap sink schemes, newSender parses the URL and returns the sender of the batches
+func registerRemoteSink(schemes []string, newSender func(u *url.URL, opts remoteOptions) (func([]remoteEntry) error, error)) {
+	factory := func(u *url.URL) (zap.Sink, error) {
+		remoteSinks.Lock()
+		defer remoteSinks.Unlock()
+
+		key := u.String()
+		if s, ok := remoteSinks.sinks[key]; ok {
+			return s, nil
+		}
+		opts, err := parseRemoteOptions(u)
+		if err != nil {
+			return nil, err
+		}
+		send, err := newSender(u, opts)
+		if err != nil {
+			return nil, err
+		}
+		s := newRemoteSink(remoteName(u), send, opts)
+		remoteSinks.sinks[key] = s
+		return s, nil
+	}
+	for _, scheme := range schemes {
+		if err := zap.RegisterSink(scheme, factory); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// flushRemoteSinks to send the buffered entries of all remote sinks
+func flushRemoteSinks() error {
+	remoteSinks.Lock()
+	sinks := make([]*remoteSink, 0, len(remoteSinks.sinks))
+	for _, s := range remoteSinks.sinks {
+		sinks = append(sinks, s)
+	}
+	remoteSinks.Unlock()
+
+	var firstErr error
+	for _, s := range sinks {
+		if err := s.Sync(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// remoteOptions are the common query parameters of the remote sink URLs
+type remoteOptions struct {
+	bufferSize    int
+	batchSize     int
+	flushInterval time.Duration
+	retries       int
+	timeout       time.Duration
+	tls           *tls.Config
+}
+
+// remoteParams are removed from the URL before it is used to send the logs
+var remoteParams = []string{"buffer", "batch", "interval", "retries", "timeout", "ca", "insecure"}
+
+func parseRemoteOptions(u *url.URL) (remoteOptions, error) {
+	q := u.Query()
+	opts := remoteOptions{
+		bufferSize:    DefaultRemoteBufferSize,
+		batchSize:     DefaultRemoteBatchSize,
+		flushInterval: DefaultRemoteFlushInterval,
+		retries:       DefaultRemoteRetries,
+		timeout:       DefaultRemoteTimeout,
+	}
+	ints := []struct {
+		name  string
+		value *int
+	}{
+		{"buffer", &opts.bufferSize},
+		{"batch", &opts.batchSize},
+		{"retries", &opts.retries},
+	}
+	for _, p := range ints {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return opts, fmt.Errorf("log: invalid %s %q of %s", p.name, v, remoteName(u))
+			}
+			*p.value = n
+		}
+	}
+	durations := []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"interval", &opts.flushInterval},
+		{"timeout", &opts.timeout},
+	}
+	for _, p := range durations {
+		if v := q.Get(p.name); v != "" {
+			ms, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || ms <= 0 {
+				return opts, fmt.Errorf("log: invalid %s %q of %s, it should be milliseconds", p.name, v, remoteName(u))
+			}
+			*p.value = time.Duration(ms) * time.Millisecond
+		}
+	}
+	if opts.bufferSize < 1 {
+		opts.bufferSize = 1
+	}
+	if opts.batchSize < 1 {
+		opts.batchSize = 1
+	}
+
+	insecure, _ := strconv.ParseBool(q.Get("insecure"))
+	opts.tls = &tls.Config{InsecureSkipVerify: insecure}
+	if ca := q.Get("ca"); ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return opts, fmt.Errorf("log: read CA file of %s error: %w", remoteName(u), err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return opts, fmt.Errorf("log: no certificate in CA file %s", ca)
+		}
+		opts.tls.RootCAs = pool
+	}
+
+	for _, p := range remoteParams {
+		q.Del(p)
+	}
+	u.RawQuery = q.Encode()
+	return opts, nil
+}
+
+// remoteName to get the URL without the credentials and parameters for the messages
+func remoteName(u *url.URL) string {
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// remoteEntry is an encoded entry and its time
+// The time is taken when zap writes the entry to the sink, which is while the entry is logged, so the entry keeps
+// its time when it is buffered or retried.
+type remoteEntry struct {
+	time time.Time
+	data []byte
+}
+
+var errRemoteSyncTimeout = errors.New("log: timeout to send the buffered logs")
+
+// remoteSink buffers the entries in a bounded queue and sends them in batches with retries
+// The entries are dropped and counted when the queue is full, so that a slow collector
+// never blocks the logging.
+type remoteSink struct {
+	name    string
+	send    func([]remoteEntry) error
+	opts    remoteOptions
+	retry   *backoff.Policy
+	queue   chan remoteEntry
+	flushCh chan struct{}
+	pending int64
+	dropped uint64
+}
+
+func newRemoteSink(name string, send func([]remoteEntry) error, opts remoteOptions) *remoteSink {
+	s := &remoteSink{
+		name: name,
+		send: send,
+		opts: opts,
+		retry: &backoff.Policy{
+			Retries:         opts.retries,
+			InitialInterval: 100 * time.Millisecond,
+			MaxInterval:     5 * time.Second,
+			Jitter:          backoff.DefaultJitter,
+		},
+		queue:   make(chan remoteEntry, opts.bufferSize),
+		flushCh: make(chan struct{}, 1),
+	}
+	go s.run()
+	return s
+}
+
+// Write implements zap.Sink, p is copied since zap reuses it
+func (s *remoteSink) Write(p []byte) (int, error) {
+	entry := remoteEntry{time: time.Now(), data: make([]byte, len(p))}
+	copy(entry.data, p)
+	atomic.AddInt64(&s.pending, 1)
+	select {
+	case s.queue <- entry:
+	default:
+		atomic.AddInt64(&s.pending, -1)
+		atomic.AddUint64(&s.dropped, 1)
+	}
+	return len(p), nil
+}
+
+// Sync implements zap.Sink, it waits until the buffered entries are sent or the timeout
+func (s *remoteSink) Sync() error {
+	select {
+	case s.flushCh <- struct{}{}:
+	default:
+	}
+	deadline := time.Now().Add(s.opts.timeout * time.Duration(s.opts.retries+1))
+	for atomic.LoadInt64(&s.pending) > 0 {
+		if time.Now().After(deadline) {
+			return errRemoteSyncTimeout
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+// Close implements zap.Sink, the sink is kept since it is shared by the loggers
+func (s *remoteSink) Close() error {
+	return s.Sync()
+}
+
+func (s *remoteSink) run() {
+	ticker := time.NewTicker(s.opts.flushInterval)
+	defer ticker.Stop()
+	var (
+		batch    []remoteEntry
+		reported uint64
+	)
+	for {
+		flush := false
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			flush = len(batch) >= s.opts.batchSize
+		case <-ticker.C:
+			flush = true
+		case <-s.flushCh:
+			// Take all queued entries
+			for len(s.queue) > 0 && len(batch) < s.opts.batchSize {
+				batch = append(batch, <-s.queue)
+			}
+			flush = true
+			if len(s.queue) > 0 {
+				// Keep flushing until the queue is empty
+				select {
+				case s.flushCh <- struct{}{}:
+				default:
+				}
+			}
+		}
+		if !flush || len(batch) == 0 {
+			continue
+		}
+
+		err := s.retry.Retry(context.Background(), func() error {
+			return s.send(batch)
+		}, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "log: send %d log entries to %s error: %v\n", len(batch), s.name, err)
+		}
+		atomic.AddInt64(&s.pending, -int64(len(batch)))
+		batch = nil
+
+		if dropped := atomic.LoadUint64(&s.dropped); dropped > reported {
+			fmt.Fprintf(os.Stderr, "log: dropped %d log entries to %s since the buffer is full\n", dropped-reported, s.name)
+			reported = dropped
+		}
+	}
+}
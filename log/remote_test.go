@@ -0,0 +1,252 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newRemoteLogger to get a logger which only writes to the remote sinks
+func newRemoteLogger(t *testing.T, opt *Option) *Logger {
+	t.Helper()
+	opt.LogLevel = "debug"
+	opt.OutputPaths = []string{os.DevNull}
+	return NewLogger(opt)
+}
+
+var syslogHeader = regexp.MustCompile(`^<(\d+)>1 \S+ \S+ remote-test \d+ - - (.*)$`)
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	logger := newRemoteLogger(t, &Option{
+		SyslogURL: fmt.Sprintf("syslog+udp://%s?app=remote-test&facility=local0", conn.LocalAddr()),
+	})
+	logger.Infow("udp info")
+	logger.Errorw("udp error")
+	if err := Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	// local0 is 16, info is 6 and error is 3
+	want := []struct {
+		pri string
+		msg string
+	}{
+		{"134", "udp info"},
+		{"131", "udp error"},
+	}
+	buf := make([]byte, 64*1024)
+	for _, w := range want {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read datagram error: %v", err)
+		}
+		// One message per datagram
+		m := syslogHeader.FindStringSubmatch(string(buf[:n]))
+		if m == nil {
+			t.Fatalf("invalid syslog message: %q", buf[:n])
+		}
+		if m[1] != w.pri || !strings.Contains(m[2], `"msg":"`+w.msg+`"`) {
+			t.Errorf("got PRI %s and %s, want PRI %s and msg %q", m[1], m[2], w.pri, w.msg)
+		}
+	}
+}
+
+func TestSyslogTCPOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	messages := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			// MSG-LEN SP SYSLOG-MSG of RFC6587
+			size, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(size))
+			if err != nil {
+				messages <- "invalid frame length " + size
+				return
+			}
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			messages <- string(msg)
+		}
+	}()
+
+	logger := newRemoteLogger(t, &Option{
+		SyslogURL: fmt.Sprintf("syslog+tcp://%s?app=remote-test&facility=user", ln.Addr()),
+	})
+	// The message contains a line break, which must not split the frame
+	logger.Warnw("tcp warn\nsecond line")
+	logger.Debugw("tcp debug")
+	if err := Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	// user is 1, warn is 4 and debug is 7
+	for _, pri := range []string{"12", "15"} {
+		select {
+		case msg := <-messages:
+			m := syslogHeader.FindStringSubmatch(msg)
+			if m == nil {
+				t.Fatalf("invalid syslog message: %q", msg)
+			}
+			if m[1] != pri {
+				t.Errorf("got PRI %s, want %s in %q", m[1], pri, msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout to receive syslog messages")
+		}
+	}
+}
+
+// logCollector is an HTTP log collector which fails the first requests
+type logCollector struct {
+	mu       sync.Mutex
+	failures int
+	requests int
+	batches  [][]map[string]interface{}
+}
+
+func (c *logCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests++
+	if c.failures > 0 {
+		c.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	var batch []map[string]interface{}
+	if err := json.Unmarshal(body, &batch); err != nil || r.Header.Get("X-Token") != "abc" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.batches = append(c.batches, batch)
+}
+
+func TestHTTPSinkBatching(t *testing.T) {
+	collector := &logCollector{}
+	srv := httptest.NewServer(collector)
+	defer srv.Close()
+
+	logger := newRemoteLogger(t, &Option{
+		HTTPSinkURL: "httplog+" + srv.URL + "/logs?batch=2&interval=60000&header=X-Token:abc",
+	})
+	for i := 0; i < 5; i++ {
+		logger.Infow("http entry", "i", i)
+	}
+	if err := Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	total := 0
+	for _, batch := range collector.batches {
+		if len(batch) > 2 {
+			t.Errorf("got a batch of %d entries, want at most 2", len(batch))
+		}
+		for _, entry := range batch {
+			if entry["msg"] != "http entry" || entry["i"] != float64(total) {
+				t.Errorf("got entry %v, want entry %d in order", entry, total)
+			}
+			total++
+		}
+	}
+	if total != 5 {
+		t.Errorf("got %d entries, want 5", total)
+	}
+}
+
+func TestHTTPSinkRetry(t *testing.T) {
+	collector := &logCollector{failures: 2}
+	srv := httptest.NewServer(collector)
+	defer srv.Close()
+
+	logger := newRemoteLogger(t, &Option{
+		HTTPSinkURL: "httplog+" + srv.URL + "/logs?retries=3&header=X-Token:abc",
+	})
+	logger.Errorw("retried entry")
+	if err := Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if collector.requests != 3 {
+		t.Errorf("got %d requests, want 2 failures and 1 success", collector.requests)
+	}
+	if len(collector.batches) != 1 || len(collector.batches[0]) != 1 || collector.batches[0][0]["msg"] != "retried entry" {
+		t.Errorf("got batches %v, want the retried entry", collector.batches)
+	}
+}
+
+func TestSyslogTimestamp(t *testing.T) {
+	s := &syslogSender{facility: 1, hostname: "host", appName: "remote-test", levelKey: "level"}
+	loc := time.FixedZone("UTC+8", 8*3600)
+	e := remoteEntry{
+		time: time.Date(2020, 1, 2, 3, 4, 5, 6000, loc),
+		data: []byte(`{"level":"error","msg":"failed"}` + "\n"),
+	}
+	want := fmt.Sprintf(`<11>1 2020-01-02T03:04:05.000006+08:00 host remote-test %d - - {"level":"error","msg":"failed"}`, os.Getpid())
+	if got := string(s.format(e)); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// The entries keep the time of writing while they are buffered
+	var (
+		mu   sync.Mutex
+		sent []remoteEntry
+	)
+	sink := newRemoteSink("test", func(batch []remoteEntry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, batch...)
+		return nil
+	}, remoteOptions{bufferSize: 10, batchSize: 10, flushInterval: time.Hour, timeout: time.Second})
+	before := time.Now()
+	_, _ = sink.Write([]byte("buffered\n"))
+	written := time.Now()
+	time.Sleep(20 * time.Millisecond)
+	if err := sink.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || sent[0].time.Before(before) || sent[0].time.After(written) {
+		t.Errorf("got entries %v, want the time between %s and %s", sent, before, written)
+	}
+}
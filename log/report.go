@@ -0,0 +1,58 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// reportInterval is the min interval between the reports of the same internal error
+const reportInterval = time.Minute
+
+// internalErrors reports the errors of the sinks which can not be returned to the caller, such as the errors
+// of writing in background
+var internalErrors = newErrorReporter(zapcore.Lock(os.Stderr), reportInterval)
+
+// errorReporter writes the internal errors of the logger to the output, the errors of the same key are
+// written at most once per interval, and the suppressed amount is written with the next one
+type errorReporter struct {
+	mu         sync.Mutex
+	output     zapcore.WriteSyncer
+	interval   time.Duration
+	last       map[string]time.Time
+	suppressed map[string]int
+}
+
+func newErrorReporter(output zapcore.WriteSyncer, interval time.Duration) *errorReporter {
+	return &errorReporter{
+		output:     output,
+		interval:   interval,
+		last:       map[string]time.Time{},
+		suppressed: map[string]int{},
+	}
+}
+
+// report to write the error unless an error of the key is written in the interval, and get whether it is written
+// The key should be bounded, such as the kind of the error and the name of the sink.
+func (r *errorReporter) report(key string, format string, args ...interface{}) bool {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if last, ok := r.last[key]; ok && now.Sub(last) < r.interval {
+		r.suppressed[key]++
+		return false
+	}
+	r.last[key] = now
+	msg := fmt.Sprintf(format, args...)
+	if n := r.suppressed[key]; n > 0 {
+		msg += fmt.Sprintf(", %d similar errors are suppressed", n)
+		delete(r.suppressed, key)
+	}
+	fmt.Fprintf(r.output, "log: %s\n", msg)
+	_ = r.output.Sync()
+	return true
+}
@@ -0,0 +1,26 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestErrorReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := newErrorReporter(zapcore.AddSync(&buf), 50*time.Millisecond)
+
+	r.report("write", "write error %d", 1)
+	r.report("write", "write error %d", 2)
+	r.report("write", "write error %d", 3)
+	r.report("send", "send error")
+	time.Sleep(60 * time.Millisecond)
+	r.report("write", "write error %d", 4)
+
+	want := "log: write error 1\nlog: send error\nlog: write error 4, 2 similar errors are suppressed\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,334 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Layout of the time in the backup file names, such as app-2006-01-02T15-04-05.000.log
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+const compressSuffix = ".gz"
+
+type rotateConfig struct {
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+}
+
+func (c rotateConfig) enabled() bool {
+	return c.maxSize > 0 || c.interval > 0
+}
+
+// rotateSinks keeps one writer per file, so that all loggers of the same file share the rotation
+var rotateSinks = struct {
+	sync.Mutex
+	writers map[string]*rotateWriter
+}{writers: map[string]*rotateWriter{}}
+
+// registerRotateSink to register the rotating file as a zap sink with its own scheme, and get the sink URL
+// The config of the file is replaced if the file is registered already.
+func registerRotateSink(path string, cfg rotateConfig) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	rotateSinks.Lock()
+	defer rotateSinks.Unlock()
+
+	if w, ok := rotateSinks.writers[path]; ok {
+		w.setConfig(cfg)
+		return w.url
+	}
+	scheme := fmt.Sprintf("rotate-%d", len(rotateSinks.writers)+1)
+	w := &rotateWriter{
+		path:      path,
+		cfg:       cfg,
+		url:       scheme + "://",
+		cleanupCh: make(chan struct{}, 1),
+	}
+	err := zap.RegisterSink(scheme, func(*url.URL) (zap.Sink, error) {
+		return w, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	rotateSinks.writers[path] = w
+	return w.url
+}
+
+// rotateWriter is a zap sink which writes to the file and rotates it by size or time
+// The rotated backups are compressed and removed in background.
+type rotateWriter struct {
+	mu          sync.Mutex
+	path        string
+	cfg         rotateConfig
+	url         string
+	file        *os.File
+	size        int64
+	nextRotate  time.Time
+	cleanupOnce sync.Once
+	cleanupCh   chan struct{}
+}
+
+func (w *rotateWriter) setConfig(cfg rotateConfig) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cfg = cfg
+	if w.file != nil && cfg.interval > 0 {
+		w.nextRotate = nextRotateTime(time.Now(), cfg.interval)
+	}
+}
+
+// Write implements zap.Sink
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if (w.cfg.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.cfg.maxSize) ||
+		(w.cfg.interval > 0 && !time.Now().Before(w.nextRotate)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sync implements zap.Sink
+func (w *rotateWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close implements zap.Sink, the file is kept open since it is shared by the loggers
+func (w *rotateWriter) Close() error {
+	return nil
+}
+
+// open opens the existing file or creates it
+func (w *rotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	if w.cfg.interval > 0 {
+		// The existing file is rotated at once if it is written in the previous period
+		start := time.Now()
+		if w.size > 0 {
+			start = info.ModTime()
+		}
+		w.nextRotate = nextRotateTime(start, w.cfg.interval)
+	}
+	return nil
+}
+
+func (w *rotateWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	if err := os.Rename(w.path, w.newBackupName(time.Now())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.cleanupOnce.Do(func() {
+		go w.runCleanup()
+	})
+	select {
+	case w.cleanupCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// newBackupName to get the name of a new backup, the time is moved forward if the name is taken,
+// since the file may be rotated more than once in a millisecond
+func (w *rotateWriter) newBackupName(t time.Time) string {
+	for {
+		name := w.backupName(t)
+		if !fileExists(name) && !fileExists(name+compressSuffix) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (w *rotateWriter) backupName(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+	return filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+}
+
+func (w *rotateWriter) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(w.path)
+	name := filepath.Base(w.path)
+	ext = filepath.Ext(name)
+	prefix = strings.TrimSuffix(name, ext) + "-"
+	return dir, prefix, ext
+}
+
+func (w *rotateWriter) runCleanup() {
+	for range w.cleanupCh {
+		if err := w.cleanup(); err != nil {
+			internalErrors.report("rotate:"+w.path, "clean up the backups of %s error: %v", w.path, err)
+		}
+	}
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+// cleanup removes the expired backups and compresses the others
+func (w *rotateWriter) cleanup() error {
+	w.mu.Lock()
+	cfg := w.cfg
+	w.mu.Unlock()
+
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+	var remove, keep []backupFile
+	cutoff := time.Now().Add(-cfg.maxAge)
+	for i, b := range backups {
+		if (cfg.maxBackups > 0 && i >= cfg.maxBackups) || (cfg.maxAge > 0 && b.time.Before(cutoff)) {
+			remove = append(remove, b)
+		} else {
+			keep = append(keep, b)
+		}
+	}
+
+	var errs []string
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	if cfg.compress {
+		for _, b := range keep {
+			if strings.HasSuffix(b.path, compressSuffix) {
+				continue
+			}
+			if err := compressFile(b.path, b.path+compressSuffix); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// backups to get the backup files, the newest first
+func (w *rotateWriter) backups() ([]backupFile, error) {
+	dir, prefix, ext := w.nameParts()
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []backupFile
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), prefix) {
+			continue
+		}
+		ts := strings.TrimPrefix(f.Name(), prefix)
+		ts = strings.TrimSuffix(ts, compressSuffix)
+		if !strings.HasSuffix(ts, ext) {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(ts, ext), time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, f.Name()), time: t})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+	return backups, nil
+}
+
+func compressFile(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(dst)
+		}
+	}()
+
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, in); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
+
+// nextRotateTime to get the end of the period of t, the periods are aligned to the local midnight
+func nextRotateTime(t time.Time, interval time.Duration) time.Time {
+	if interval > 24*time.Hour {
+		return t.Add(interval)
+	}
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	return midnight.Add((t.Sub(midnight)/interval + 1) * interval)
+}
@@ -0,0 +1,233 @@
+package log
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRotateWriter to get a writer of app.log in a temp dir, the dir should be removed by the caller
+func newTestRotateWriter(t *testing.T, cfg rotateConfig) (*rotateWriter, string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &rotateWriter{
+		path:      filepath.Join(dir, "app.log"),
+		cfg:       cfg,
+		cleanupCh: make(chan struct{}, 1),
+	}, dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRotateBySize(t *testing.T) {
+	w, dir := newTestRotateWriter(t, rotateConfig{maxSize: 10})
+	defer os.RemoveAll(dir)
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The writes are in the same millisecond, the backups must not overwrite each other
+	if len(backups) != 2 {
+		t.Fatalf("got %d backups, want 2", len(backups))
+	}
+	if got := readFile(t, backups[0].path); got != "second\n" {
+		t.Errorf("got newest backup %q, want second", got)
+	}
+	if got := readFile(t, backups[1].path); got != "first\n" {
+		t.Errorf("got oldest backup %q, want first", got)
+	}
+	if got := readFile(t, w.path); got != "third\n" {
+		t.Errorf("got current file %q, want third", got)
+	}
+}
+
+func TestRotateByTime(t *testing.T) {
+	w, dir := newTestRotateWriter(t, rotateConfig{interval: 50 * time.Millisecond})
+	defer os.RemoveAll(dir)
+
+	if _, err := w.Write([]byte("before\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Until(w.nextRotate) + 10*time.Millisecond)
+	if _, err := w.Write([]byte("after\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || readFile(t, backups[0].path) != "before\n" {
+		t.Fatalf("got backups %v, want the one before the rotation", backups)
+	}
+	if got := readFile(t, w.path); got != "after\n" {
+		t.Errorf("got current file %q, want after", got)
+	}
+}
+
+func TestNextRotateTime(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	now := time.Date(2020, 5, 13, 10, 20, 30, 0, loc)
+	tests := []struct {
+		interval time.Duration
+		want     time.Time
+	}{
+		{time.Hour, time.Date(2020, 5, 13, 11, 0, 0, 0, loc)},
+		{6 * time.Hour, time.Date(2020, 5, 13, 12, 0, 0, 0, loc)},
+		{24 * time.Hour, time.Date(2020, 5, 14, 0, 0, 0, 0, loc)},
+		{48 * time.Hour, now.Add(48 * time.Hour)},
+	}
+	for _, tt := range tests {
+		if got := nextRotateTime(now, tt.interval); !got.Equal(tt.want) {
+			t.Errorf("interval %s: got %s, want %s", tt.interval, got, tt.want)
+		}
+	}
+}
+
+// writeBackups to create the backups written at the times, the newest first
+func writeBackups(t *testing.T, w *rotateWriter, times ...time.Time) []string {
+	t.Helper()
+	var paths []string
+	for _, tm := range times {
+		path := w.backupName(tm)
+		if err := ioutil.WriteFile(path, []byte(tm.Format(time.RFC3339)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestCleanupPrunesBackups(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		cfg  rotateConfig
+		want []int
+	}{
+		{"keep all", rotateConfig{}, []int{0, 1, 2, 3}},
+		{"max backups", rotateConfig{maxBackups: 2}, []int{0, 1}},
+		{"max age", rotateConfig{maxAge: 24 * time.Hour}, []int{0, 1}},
+		{"both", rotateConfig{maxBackups: 1, maxAge: 24 * time.Hour}, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, dir := newTestRotateWriter(t, tt.cfg)
+			defer os.RemoveAll(dir)
+			paths := writeBackups(t, w, now.Add(-time.Hour), now.Add(-2*time.Hour), now.Add(-48*time.Hour), now.Add(-72*time.Hour))
+			// Other files in the dir are not touched
+			other := filepath.Join(dir, "app-notes.log")
+			if err := ioutil.WriteFile(other, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := w.cleanup(); err != nil {
+				t.Fatal(err)
+			}
+			backups, err := w.backups()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(backups) != len(tt.want) {
+				t.Fatalf("got %d backups, want %d", len(backups), len(tt.want))
+			}
+			for i, idx := range tt.want {
+				if backups[i].path != paths[idx] {
+					t.Errorf("got backup %s, want %s", backups[i].path, paths[idx])
+				}
+			}
+			if !fileExists(other) {
+				t.Error("got the other file removed")
+			}
+		})
+	}
+}
+
+func TestCleanupCompressesBackups(t *testing.T) {
+	w, dir := newTestRotateWriter(t, rotateConfig{compress: true, maxBackups: 1})
+	defer os.RemoveAll(dir)
+	now := time.Now()
+	paths := writeBackups(t, w, now.Add(-time.Hour), now.Add(-2*time.Hour))
+
+	if err := w.cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(paths[0]) || fileExists(paths[1]) || fileExists(paths[1]+compressSuffix) {
+		t.Fatal("got the backups kept, want the newest compressed and the other removed")
+	}
+	f, err := os.Open(paths[0] + compressSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != now.Add(-time.Hour).Format(time.RFC3339) {
+		t.Errorf("got compressed content %q", b)
+	}
+
+	// The compressed backups are counted and kept as they are
+	if err := w.cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	backups, err := w.backups()
+	if err != nil || len(backups) != 1 || !strings.HasSuffix(backups[0].path, compressSuffix) {
+		t.Errorf("got backups %v and error %v, want the compressed one", backups, err)
+	}
+}
+
+func TestRotateThroughLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "app.log")
+
+	logger := NewLogger(&Option{LogLevel: "info", LogFile: file, OutputPaths: []string{os.DevNull}, RotateInterval: 3600})
+	logger.Infow("rotated sink")
+	_ = logger.Sync()
+	if got := readFile(t, file); !strings.Contains(got, "rotated sink") {
+		t.Errorf("got %q, want the entry written by the rotating sink", got)
+	}
+
+	// The loggers of the same file share the writer, and the config is replaced
+	url := registerRotateSink(file, rotateConfig{maxSize: 1})
+	if url != registerRotateSink(file, rotateConfig{maxSize: 2}) {
+		t.Error("got different sinks for the same file")
+	}
+	rotateSinks.Lock()
+	w := rotateSinks.writers[file]
+	rotateSinks.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cfg.maxSize != 2 {
+		t.Errorf("got max size %d, want the new config", w.cfg.maxSize)
+	}
+}
@@ -0,0 +1,159 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Default sampling, the same as zap.NewProductionConfig
+const (
+	DefaultSamplingInitial    = 100
+	DefaultSamplingThereafter = 100
+	DefaultSamplingTick       = time.Second
+)
+
+type samplingConfig struct {
+	disabled    bool
+	initial     int
+	thereafter  int
+	tick        time.Duration
+	dedupWindow time.Duration
+}
+
+// wrap to wrap the core with the sampler and the deduplication
+// The deduplication is outside, so that its summaries are redacted and sampled as well.
+func (c samplingConfig) wrap(core zapcore.Core, wrapInner func(zapcore.Core) zapcore.Core) zapcore.Core {
+	if !c.disabled {
+		core = zapcore.NewSampler(core, c.tick, c.initial, c.thereafter)
+	}
+	core = wrapInner(core)
+	if c.dedupWindow > 0 {
+		core = &dedupCore{
+			Core: core,
+			state: &dedupState{
+				root:    core,
+				window:  c.dedupWindow,
+				entries: map[dedupKey]*dedupEntry{},
+			},
+		}
+	}
+	return core
+}
+
+// dedupCore suppresses the same messages in the window, and writes a summary like
+// "suppressed 10 similar messages" when the window ends.
+// The summaries are written by the next entry after the window or by Sync.
+// The messages at DPanicLevel or above are never suppressed.
+// The cores got by With share the state, the summaries are written by the root core without the fields
+// of With, since the suppressed entries may come from different cores.
+type dedupCore struct {
+	zapcore.Core
+	state *dedupState
+}
+
+type dedupState struct {
+	// root is the wrapped core before any With
+	root      zapcore.Core
+	mu        sync.Mutex
+	window    time.Duration
+	entries   map[dedupKey]*dedupEntry
+	lastSweep time.Time
+}
+
+type dedupKey struct {
+	level   zapcore.Level
+	logger  string
+	message string
+}
+
+type dedupEntry struct {
+	ent        zapcore.Entry
+	suppressed int
+}
+
+// With implements zapcore.Core
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{Core: c.Core.With(fields), state: c.state}
+}
+
+// Check implements zapcore.Core
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zapcore.DPanicLevel || !c.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+
+	key := dedupKey{level: ent.Level, logger: ent.LoggerName, message: ent.Message}
+	s := c.state
+	s.mu.Lock()
+	summaries := s.sweep(ent.Time, false)
+	e, ok := s.entries[key]
+	suppress := ok && ent.Time.Sub(e.ent.Time) < s.window
+	if suppress {
+		e.suppressed++
+	} else {
+		if ok && e.suppressed > 0 {
+			summaries = append(summaries, *e)
+		}
+		s.entries[key] = &dedupEntry{ent: ent}
+	}
+	s.mu.Unlock()
+
+	s.writeSummaries(summaries, ent.Time)
+	if suppress {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// Sync implements zapcore.Core, it writes all pending summaries first
+func (c *dedupCore) Sync() error {
+	now := time.Now()
+	s := c.state
+	s.mu.Lock()
+	summaries := s.sweep(now, true)
+	s.mu.Unlock()
+
+	s.writeSummaries(summaries, now)
+	return c.Core.Sync()
+}
+
+// sweep to get the summaries of the expired entries and remove them, or of all entries if all is true
+// It scans the entries at most once per half window unless all is true.
+func (s *dedupState) sweep(now time.Time, all bool) []dedupEntry {
+	if !all && now.Sub(s.lastSweep) < s.window/2 {
+		return nil
+	}
+	s.lastSweep = now
+
+	var summaries []dedupEntry
+	for key, e := range s.entries {
+		expired := now.Sub(e.ent.Time) >= s.window
+		if (all || expired) && e.suppressed > 0 {
+			summaries = append(summaries, *e)
+			e.suppressed = 0
+		}
+		if expired {
+			delete(s.entries, key)
+		}
+	}
+	return summaries
+}
+
+func (s *dedupState) writeSummaries(summaries []dedupEntry, now time.Time) {
+	for _, e := range summaries {
+		ent := e.ent
+		ent.Time = now
+		ent.Message = fmt.Sprintf("suppressed %d similar messages", e.suppressed)
+		ent.Stack = ""
+		if ce := s.root.Check(ent, nil); ce != nil {
+			ce.Write(
+				zap.String("suppressedMessage", e.ent.Message),
+				zap.Int("suppressed", e.suppressed),
+			)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newDedupLogger to get a logger with the deduplication only, and its entries
+func newDedupLogger(window time.Duration) (*zap.Logger, *observer.ObservedLogs) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	cfg := samplingConfig{disabled: true, dedupWindow: window}
+	return zap.New(cfg.wrap(observed, func(core zapcore.Core) zapcore.Core { return core })), logs
+}
+
+func TestSampling(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	cfg := samplingConfig{initial: 2, thereafter: 3, tick: time.Minute}
+	logger := zap.New(cfg.wrap(observed, func(core zapcore.Core) zapcore.Core { return core }))
+	for i := 0; i < 8; i++ {
+		logger.Info("sampled")
+	}
+	logger.Warn("sampled")
+	// The first 2, then the 5th and the 8th, and the other level
+	if logs.Len() != 5 {
+		t.Errorf("got %d entries, want 5", logs.Len())
+	}
+
+	observed, logs = observer.New(zapcore.DebugLevel)
+	cfg.disabled = true
+	logger = zap.New(cfg.wrap(observed, func(core zapcore.Core) zapcore.Core { return core }))
+	for i := 0; i < 8; i++ {
+		logger.Info("sampled")
+	}
+	if logs.Len() != 8 {
+		t.Errorf("got %d entries with sampling disabled, want 8", logs.Len())
+	}
+}
+
+func TestDedupSummaryAfterWindow(t *testing.T) {
+	logger, logs := newDedupLogger(50 * time.Millisecond)
+	for i := 0; i < 4; i++ {
+		logger.Info("repeated")
+	}
+	logger.Info("other")
+	if logs.Len() != 2 {
+		t.Fatalf("got %d entries in the window, want 2", logs.Len())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	logger.Info("next")
+	summaries := logs.FilterMessage("suppressed 3 similar messages").All()
+	if len(summaries) != 1 {
+		t.Fatalf("got entries %v, want the summary written by the next entry", logs.All())
+	}
+	fields := summaries[0].ContextMap()
+	if fields["suppressedMessage"] != "repeated" || fields["suppressed"] != int64(3) {
+		t.Errorf("got summary fields %v", fields)
+	}
+	if logs.FilterMessage("next").Len() != 1 {
+		t.Error("got the next entry suppressed")
+	}
+
+	// The same message is logged again after the window
+	logger.Info("repeated")
+	if logs.FilterMessage("repeated").Len() != 2 {
+		t.Error("got the message suppressed after the window")
+	}
+}
+
+func TestDedupSummaryOnSync(t *testing.T) {
+	logger, logs := newDedupLogger(time.Minute)
+	logger.Warn("repeated")
+	logger.Warn("repeated")
+	logger.Info("repeated")
+	if logs.Len() != 2 {
+		t.Fatalf("got %d entries, want 2 since the levels are different", logs.Len())
+	}
+	_ = logger.Sync()
+	summaries := logs.FilterMessage("suppressed 1 similar messages").All()
+	if len(summaries) != 1 || summaries[0].Level != zapcore.WarnLevel {
+		t.Errorf("got entries %v, want one warn summary", logs.All())
+	}
+}
+
+func TestDedupKeepsDPanic(t *testing.T) {
+	logger, logs := newDedupLogger(time.Minute)
+	logger.DPanic("fatal")
+	logger.DPanic("fatal")
+	logger.Named("db").Info("named")
+	logger.Named("http").Info("named")
+	if logs.Len() != 4 {
+		t.Errorf("got %d entries, want 4 since dpanic and the different loggers are not deduplicated", logs.Len())
+	}
+}
+
+func TestDedupSummaryWithoutFields(t *testing.T) {
+	logger, logs := newDedupLogger(time.Minute)
+	logger.With(zap.String("request_id", "a")).Info("repeated")
+	logger.With(zap.String("request_id", "b")).Info("repeated")
+	// The summary is written by the Sync of another core
+	_ = logger.With(zap.String("request_id", "c")).Sync()
+
+	summaries := logs.FilterMessage("suppressed 1 similar messages").All()
+	if len(summaries) != 1 {
+		t.Fatalf("got entries %v, want the summary", logs.All())
+	}
+	if _, ok := summaries[0].ContextMap()["request_id"]; ok {
+		t.Errorf("got summary fields %v, want no field of the cores got by With", summaries[0].ContextMap())
+	}
+}
+
+func TestUnifySampling(t *testing.T) {
+	disable := true
+	cfg := unifySampling(&Option{SamplingInitial: 5, DisableSampling: &disable, DedupWindow: 2})
+	want := samplingConfig{
+		disabled:    true,
+		initial:     5,
+		thereafter:  DefaultSamplingThereafter,
+		tick:        DefaultSamplingTick,
+		dedupWindow: 2 * time.Second,
+	}
+	if cfg != want {
+		t.Errorf("got %+v, want %+v", cfg, want)
+	}
+}
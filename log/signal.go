@@ -0,0 +1,30 @@
+// +build !windows
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchLevelSignals switches the root level to debug on SIGUSR1, and back on SIGUSR2
+func (r *levelRegistry) watchLevelSignals() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				r.toggleDebug(sig == syscall.SIGUSR1)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
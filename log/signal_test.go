@@ -0,0 +1,101 @@
+// +build !windows
+
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWatchLevelSignals(t *testing.T) {
+	core, _ := observer.New(zapcore.DebugLevel)
+	logger := NewLoggerWithCore(core, zapcore.WarnLevel)
+	stop := logger.WatchLevelSignals()
+	defer stop()
+
+	waitLevel := func(want zapcore.Level) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for logger.AtomicLevel.Level() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("got level %s, want %s", logger.AtomicLevel.Level(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	waitLevel(zapcore.DebugLevel)
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	waitLevel(zapcore.WarnLevel)
+}
+
+func TestToggleDebug(t *testing.T) {
+	r := newLevelRegistry(zap.NewAtomicLevelAt(zapcore.ErrorLevel))
+	r.toggleDebug(true)
+	r.toggleDebug(true)
+	if r.root.Level() != zapcore.DebugLevel {
+		t.Errorf("got level %s, want debug", r.root.Level())
+	}
+	r.toggleDebug(false)
+	if r.root.Level() != zapcore.ErrorLevel {
+		t.Errorf("got level %s, want the saved error level", r.root.Level())
+	}
+	// Switching back without a saved level keeps the level
+	r.toggleDebug(false)
+	if r.root.Level() != zapcore.ErrorLevel {
+		t.Errorf("got level %s, want error", r.root.Level())
+	}
+}
+
+func TestFlushOnSignal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "async.log")
+	async := true
+	logger := NewLogger(&Option{
+		LogLevel:           "info",
+		OutputPaths:        []string{file},
+		Async:              &async,
+		AsyncFlushInterval: 60 * 1000,
+	})
+
+	raised := make(chan os.Signal, 1)
+	defer func(raise func(os.Signal)) { raiseSignal = raise }(raiseSignal)
+	raiseSignal = func(sig os.Signal) { raised <- sig }
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	FlushOnSignal(ctx)
+
+	logger.Info("before SIGTERM")
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case sig := <-raised:
+		if sig != syscall.SIGTERM {
+			t.Errorf("got raised signal %s, want SIGTERM", sig)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the signal is not raised again")
+	}
+	if b, _ := ioutil.ReadFile(file); !strings.Contains(string(b), "before SIGTERM") {
+		t.Errorf("got %q, want the buffered log", b)
+	}
+}
@@ -0,0 +1,6 @@
+package log
+
+// watchLevelSignals does nothing since there are no SIGUSR1 and SIGUSR2 on windows
+func (r *levelRegistry) watchLevelSignals() (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,197 @@
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Schemes of the syslog sinks, such as syslog+udp://127.0.0.1:514?app=demo&facility=local0
+// The TCP and TLS messages are framed by octet counting of RFC6587.
+const (
+	SyslogUDPScheme = "syslog+udp"
+	SyslogTCPScheme = "syslog+tcp"
+	SyslogTLSScheme = "syslog+tls"
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+var syslogSeverities = map[zapcore.Level]int{
+	zapcore.DebugLevel:  7,
+	zapcore.InfoLevel:   6,
+	zapcore.WarnLevel:   4,
+	zapcore.ErrorLevel:  3,
+	zapcore.DPanicLevel: 2,
+	zapcore.PanicLevel:  1,
+	zapcore.FatalLevel:  0,
+}
+
+func init() {
+	registerRemoteSink([]string{SyslogUDPScheme, SyslogTCPScheme, SyslogTLSScheme}, newSyslogSender)
+}
+
+// syslogSender sends the entries as RFC5424 messages
+type syslogSender struct {
+	network  string
+	address  string
+	tls      *tls.Config
+	timeout  time.Duration
+	facility int
+	hostname string
+	appName  string
+	levelKey string
+	mu       sync.Mutex
+	conn     net.Conn
+}
+
+func newSyslogSender(u *url.URL, opts remoteOptions) (func([]remoteEntry) error, error) {
+	q := u.Query()
+	s := &syslogSender{
+		address:  u.Host,
+		timeout:  opts.timeout,
+		appName:  q.Get("app"),
+		levelKey: q.Get("levelKey"),
+	}
+	switch u.Scheme {
+	case SyslogUDPScheme:
+		s.network = "udp"
+	case SyslogTCPScheme:
+		s.network = "tcp"
+	case SyslogTLSScheme:
+		s.network = "tcp"
+		s.tls = opts.tls
+		if s.tls.ServerName == "" {
+			s.tls.ServerName = u.Hostname()
+		}
+	}
+
+	facility := q.Get("facility")
+	if facility == "" {
+		facility = "user"
+	}
+	f, ok := syslogFacilities[strings.ToLower(facility)]
+	if !ok {
+		return nil, fmt.Errorf("log: invalid syslog facility %q", facility)
+	}
+	s.facility = f
+	if s.appName == "" {
+		s.appName = filepath.Base(os.Args[0])
+	}
+	if s.levelKey == "" {
+		s.levelKey = "level"
+	}
+	s.hostname, _ = os.Hostname()
+	if s.hostname == "" {
+		s.hostname = "-"
+	}
+	return s.send, nil
+}
+
+func (s *syslogSender) send(batch []remoteEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial()
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	var buf bytes.Buffer
+	for _, entry := range batch {
+		msg := s.format(entry)
+		if s.network == "udp" {
+			// One datagram per message
+			if err := s.write(msg); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintf(&buf, "%d %s", len(msg), msg)
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return s.write(buf.Bytes())
+}
+
+func (s *syslogSender) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.timeout}
+	if s.tls != nil {
+		return tls.DialWithDialer(dialer, s.network, s.address, s.tls)
+	}
+	return dialer.Dial(s.network, s.address)
+}
+
+// write writes to the connection, which is closed on error and dialed again by the next send
+func (s *syslogSender) write(b []byte) error {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	if _, err := s.conn.Write(b); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// format formats the entry as <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG,
+// TIMESTAMP is the time of the entry rather than the time it is sent
+func (s *syslogSender) format(e remoteEntry) []byte {
+	entry := bytes.TrimRight(e.data, "\r\n")
+	pri := s.facility*8 + syslogSeverities[levelOfEntry(entry, s.levelKey)]
+	header := fmt.Sprintf("<%d>1 %s %s %s %d - - ",
+		pri, e.time.Format("2006-01-02T15:04:05.000000Z07:00"), s.hostname, s.appName, os.Getpid())
+	return append([]byte(header), entry...)
+}
+
+var (
+	ansiPattern   = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	logfmtPattern = regexp.MustCompile(`(?:^|\s)(\S+)=("(?:[^"\\]|\\.)*"|\S*)`)
+)
+
+// levelOfEntry to get the level of the encoded entry, it supports the json, logfmt and console encodings
+// The level is info if it is not found.
+func levelOfEntry(entry []byte, levelKey string) zapcore.Level {
+	var value string
+	switch {
+	case len(entry) > 0 && entry[0] == '{':
+		var fields map[string]interface{}
+		if json.Unmarshal(entry, &fields) == nil {
+			value, _ = fields[levelKey].(string)
+		}
+	case bytes.Contains(entry, []byte(levelKey+"=")):
+		for _, m := range logfmtPattern.FindAllSubmatch(entry, -1) {
+			if string(m[1]) == levelKey {
+				value = strings.Trim(string(m[2]), `"`)
+				break
+			}
+		}
+	default:
+		// Console encoding: time, level, ... separated by tabs
+		parts := strings.SplitN(string(entry), "\t", 3)
+		if len(parts) > 1 {
+			value = ansiPattern.ReplaceAllString(parts[1], "")
+		}
+	}
+	if lvl, err := parseLevel(value); err == nil {
+		return lvl
+	}
+	return zapcore.InfoLevel
+}
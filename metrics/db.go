@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/uhhc/sdk-common-go/db"
+)
+
+const dbStartKey = "metrics:start_time"
+
+// InstrumentDB to collect the queries and the connection pool stats of the client, name is the value of
+// the label "db", which tells the clients apart
+// The queries are collected by the gorm callbacks of create, query, update, delete and row query,
+// gorm.ErrRecordNotFound is not counted as an error. It should be called once for a client.
+func (m *Metrics) InstrumentDB(client *db.DbClient, name string) error {
+	if client == nil || client.DB == nil {
+		return errors.New("metrics: db is not connected")
+	}
+
+	pool := newDBPoolCollector(m, client, name)
+	if err := m.registerer.Register(pool); err != nil {
+		return err
+	}
+
+	callback := client.DB.Callback()
+	callback.Create().Before("gorm:begin_transaction").Register("metrics:before_create", beforeDBQuery)
+	callback.Create().After("gorm:commit_or_rollback_transaction").Register("metrics:after_create", m.afterDBQuery(name, "create"))
+	callback.Query().Before("gorm:query").Register("metrics:before_query", beforeDBQuery)
+	callback.Query().After("gorm:after_query").Register("metrics:after_query", m.afterDBQuery(name, "query"))
+	callback.Update().Before("gorm:begin_transaction").Register("metrics:before_update", beforeDBQuery)
+	callback.Update().After("gorm:commit_or_rollback_transaction").Register("metrics:after_update", m.afterDBQuery(name, "update"))
+	callback.Delete().Before("gorm:begin_transaction").Register("metrics:before_delete", beforeDBQuery)
+	callback.Delete().After("gorm:commit_or_rollback_transaction").Register("metrics:after_delete", m.afterDBQuery(name, "delete"))
+	callback.RowQuery().Before("gorm:row_query").Register("metrics:before_row_query", beforeDBQuery)
+	callback.RowQuery().After("gorm:row_query").Register("metrics:after_row_query", m.afterDBQuery(name, "row_query"))
+	return nil
+}
+
+func beforeDBQuery(scope *gorm.Scope) {
+	scope.InstanceSet(dbStartKey, time.Now())
+}
+
+func (m *Metrics) afterDBQuery(name, op string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		v, ok := scope.InstanceGet(dbStartKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		table := scope.TableName()
+		m.dbQueries.WithLabelValues(name, op, table).Inc()
+		m.dbDuration.WithLabelValues(name, op, table).Observe(time.Since(start).Seconds())
+		if err := scope.DB().Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+			m.dbErrors.WithLabelValues(name, op, table).Inc()
+		}
+	}
+}
+
+// dbPoolCollector collects the stats of the connection pool when it is scraped
+type dbPoolCollector struct {
+	client *db.DbClient
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+func newDBPoolCollector(m *Metrics, client *db.DbClient, name string) *dbPoolCollector {
+	labels := prometheus.Labels{"db": name}
+	for k, v := range m.constLabels {
+		labels[k] = v
+	}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(m.namespace, "db_pool", metric), help, nil, labels)
+	}
+	return &dbPoolCollector{
+		client:            client,
+		maxOpen:           desc("max_open_connections", "Maximum number of open connections."),
+		open:              desc("open_connections", "Number of open connections."),
+		inUse:             desc("in_use_connections", "Number of connections in use."),
+		idle:              desc("idle_connections", "Number of idle connections."),
+		waitCount:         desc("wait_count_total", "Number of connections waited for."),
+		waitDuration:      desc("wait_duration_seconds_total", "Time blocked waiting for new connections."),
+		maxIdleClosed:     desc("max_idle_closed_total", "Number of connections closed due to the max idle connections."),
+		maxLifetimeClosed: desc("max_lifetime_closed_total", "Number of connections closed due to the max connection lifetime."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *dbPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxLifetimeClosed
+}
+
+// Collect implements prometheus.Collector
+func (c *dbPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.DB.DB().Stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
@@ -0,0 +1,16 @@
+module github.com/uhhc/sdk-common-go/metrics
+
+go 1.13
+
+require (
+	github.com/go-resty/resty/v2 v2.3.0
+	github.com/jinzhu/gorm v1.9.11
+	github.com/prometheus/client_golang v1.10.0
+	github.com/uhhc/sdk-common-go v0.0.0-00010101000000-000000000000
+	go.mongodb.org/mongo-driver v1.1.3
+)
+
+// The SDK is developed together with the metrics in this repository, so the SDK is replaced by the parent
+// directory and the version above is a placeholder. The replace is ignored by the importers, so the SDK has to be
+// tagged first and required by `make release-metrics SDK_VERSION=<tag>` before the metrics are tagged.
+replace github.com/uhhc/sdk-common-go => ../
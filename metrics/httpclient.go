@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/uhhc/sdk-common-go/httpclient"
+)
+
+// HTTPMiddleware to get a middleware which collects every attempt of the requests by host, method and status,
+// the status is "error" if no response is received
+// The latency is measured until the response headers are received.
+func (m *Metrics) HTTPMiddleware() httpclient.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			host := req.URL.Host
+			status := "error"
+			if err == nil {
+				status = strconv.Itoa(resp.StatusCode)
+			}
+			m.httpRequests.WithLabelValues(host, req.Method, status).Inc()
+			m.httpDuration.WithLabelValues(host, req.Method).Observe(time.Since(start).Seconds())
+			return resp, err
+		})
+	}
+}
+
+// InstrumentHTTPClient to wrap the transport of the client with HTTPMiddleware
+// It should be called after the TLS and proxy settings are set, see httpclient.Use.
+func (m *Metrics) InstrumentHTTPClient(client *resty.Client) *resty.Client {
+	return httpclient.Use(client, m.HTTPMiddleware())
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultNamespace is the prefix of the metric names
+const DefaultNamespace = "sdk"
+
+// Metrics collects the metrics of the SDK clients
+// Nothing is collected unless the clients are instrumented, and the collectors are only registered
+// on the registerer given to New.
+type Metrics struct {
+	registerer  prometheus.Registerer
+	namespace   string
+	constLabels prometheus.Labels
+
+	mongoOps      *prometheus.CounterVec
+	mongoErrors   *prometheus.CounterVec
+	mongoDuration *prometheus.HistogramVec
+
+	dbQueries  *prometheus.CounterVec
+	dbErrors   *prometheus.CounterVec
+	dbDuration *prometheus.HistogramVec
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+}
+
+type options struct {
+	namespace   string
+	buckets     []float64
+	constLabels prometheus.Labels
+}
+
+// Option changes the metrics
+type Option func(*options)
+
+// WithNamespace to set the prefix of the metric names, DefaultNamespace by default
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithBuckets to set the buckets of the latency histograms in seconds, prometheus.DefBuckets by default
+func WithBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// WithConstLabels to add the labels to all metrics, such as the name of the service
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(o *options) {
+		o.constLabels = labels
+	}
+}
+
+// New to get the metrics whose collectors are registered on registerer
+// Example:
+//
+// 		registry := prometheus.NewRegistry()
+// 		m, err := metrics.New(registry)
+// 		if err != nil {
+// 			panic(err)
+// 		}
+// 		m.InstrumentMongoClient(mongo)
+// 		if err := m.InstrumentDB(dbClient, "main"); err != nil {
+// 			panic(err)
+// 		}
+// 		client := m.InstrumentHTTPClient(httpclient.NewClient())
+//
+// 		http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+//
+func New(registerer prometheus.Registerer, opts ...Option) (*Metrics, error) {
+	o := &options{
+		namespace: DefaultNamespace,
+		buckets:   prometheus.DefBuckets,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	counter := func(subsystem, name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Subsystem:   subsystem,
+			Name:        name,
+			Help:        help,
+			ConstLabels: o.constLabels,
+		}, labels)
+	}
+	histogram := func(subsystem, name, help string, labels ...string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Subsystem:   subsystem,
+			Name:        name,
+			Help:        help,
+			ConstLabels: o.constLabels,
+			Buckets:     o.buckets,
+		}, labels)
+	}
+
+	m := &Metrics{
+		registerer:  registerer,
+		namespace:   o.namespace,
+		constLabels: o.constLabels,
+
+		mongoOps:      counter("mongodb", "operations_total", "Number of MongoDB operations.", "operation", "collection"),
+		mongoErrors:   counter("mongodb", "operation_errors_total", "Number of failed MongoDB operations.", "operation", "collection"),
+		mongoDuration: histogram("mongodb", "operation_duration_seconds", "Latency of MongoDB operations.", "operation", "collection"),
+
+		dbQueries:  counter("db", "queries_total", "Number of database queries.", "db", "operation", "table"),
+		dbErrors:   counter("db", "query_errors_total", "Number of failed database queries.", "db", "operation", "table"),
+		dbDuration: histogram("db", "query_duration_seconds", "Latency of database queries.", "db", "operation", "table"),
+
+		httpRequests: counter("http_client", "requests_total", "Number of HTTP client requests.", "host", "method", "status"),
+		httpDuration: histogram("http_client", "request_duration_seconds", "Latency of HTTP client requests until the response headers.", "host", "method"),
+	}
+	for _, c := range []prometheus.Collector{
+		m.mongoOps, m.mongoErrors, m.mongoDuration,
+		m.dbQueries, m.dbErrors, m.dbDuration,
+		m.httpRequests, m.httpDuration,
+	} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
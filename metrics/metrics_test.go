@@ -0,0 +1,227 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	// use sqlite as the database of the tests
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/uhhc/sdk-common-go/db"
+	"github.com/uhhc/sdk-common-go/httpclient"
+)
+
+// metricValue to get the value of the counter, the gauge or the sample count of the histogram with the labels,
+// the value is -1 if the metric is not found
+func metricValue(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			got := map[string]string{}
+			for _, pair := range metric.GetLabel() {
+				got[pair.GetName()] = pair.GetValue()
+			}
+			for k, v := range labels {
+				if got[k] != v {
+					continue metrics
+				}
+			}
+			switch {
+			case metric.Counter != nil:
+				return metric.GetCounter().GetValue()
+			case metric.Gauge != nil:
+				return metric.GetGauge().GetValue()
+			case metric.Histogram != nil:
+				return float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return -1
+}
+
+func TestNew(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m, err := New(registry, WithNamespace("app"), WithConstLabels(prometheus.Labels{"service": "order"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.observeMongo("find", "users", time.Millisecond, nil)
+	if got := metricValue(t, registry, "app_mongodb_operations_total", map[string]string{"service": "order"}); got != 1 {
+		t.Errorf("got %v operations, want 1 with the namespace and the const labels", got)
+	}
+
+	// The collectors are already registered
+	if _, err := New(registry, WithNamespace("app")); err == nil {
+		t.Error("got no error, want the duplicate registration")
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host := server.Listener.Addr().String()
+
+	registry := prometheus.NewRegistry()
+	m, err := New(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := m.InstrumentHTTPClient(httpclient.NewClient())
+	for _, path := range []string{"/", "/", "/missing"} {
+		if _, err := client.R().Get(server.URL + path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.R().Post(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL, _ := url.Parse(closed.URL)
+	closed.Close()
+	_, _ = client.R().Get(closed.URL)
+
+	tests := []struct {
+		host, method, status string
+		want                 float64
+	}{
+		{host, "GET", "200", 2},
+		{host, "GET", "404", 1},
+		{host, "POST", "200", 1},
+		{closedURL.Host, "GET", "error", 1},
+	}
+	for _, tt := range tests {
+		labels := map[string]string{"host": tt.host, "method": tt.method, "status": tt.status}
+		if got := metricValue(t, registry, "sdk_http_client_requests_total", labels); got != tt.want {
+			t.Errorf("%v: got %v requests, want %v", labels, got, tt.want)
+		}
+	}
+	if got := metricValue(t, registry, "sdk_http_client_request_duration_seconds", map[string]string{"host": host, "method": "GET"}); got != 3 {
+		t.Errorf("got %v observed durations, want 3", got)
+	}
+}
+
+type testOrder struct {
+	ID     uint `gorm:"primary_key"`
+	Status string
+}
+
+func TestInstrumentDB(t *testing.T) {
+	gormDB, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gormDB.Close()
+	// The query of the unknown column fails on purpose
+	gormDB.LogMode(false)
+	gormDB.DB().SetMaxOpenConns(3)
+	if err := gormDB.AutoMigrate(&testOrder{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	client := &db.DbClient{DB: gormDB}
+
+	registry := prometheus.NewRegistry()
+	m, err := New(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.InstrumentDB(client, "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.InstrumentDB(&db.DbClient{}, "main"); err == nil {
+		t.Error("got no error, want the db is not connected")
+	}
+
+	order := &testOrder{Status: "new"}
+	if err := gormDB.Create(order).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := gormDB.Model(order).Update("status", "paid").Error; err != nil {
+		t.Fatal(err)
+	}
+	var found testOrder
+	if err := gormDB.First(&found, order.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := gormDB.First(&found, order.ID+1).Error; !gorm.IsRecordNotFoundError(err) {
+		t.Fatalf("got error %v, want not found", err)
+	}
+	_ = gormDB.Find(&[]testOrder{}, "unknown = ?", 1).Error
+	if err := gormDB.Delete(order).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		operation string
+		want      float64
+	}{
+		{"sdk_db_queries_total", "create", 1},
+		{"sdk_db_queries_total", "update", 1},
+		{"sdk_db_queries_total", "query", 3},
+		{"sdk_db_queries_total", "delete", 1},
+		{"sdk_db_query_duration_seconds", "query", 3},
+		// The record not found is not an error
+		{"sdk_db_query_errors_total", "query", 1},
+		{"sdk_db_query_errors_total", "create", -1},
+	}
+	for _, tt := range tests {
+		labels := map[string]string{"db": "main", "operation": tt.operation, "table": "test_orders"}
+		if got := metricValue(t, registry, tt.name, labels); got != tt.want {
+			t.Errorf("%s %s: got %v, want %v", tt.name, tt.operation, got, tt.want)
+		}
+	}
+
+	if got := metricValue(t, registry, "sdk_db_pool_max_open_connections", map[string]string{"db": "main"}); got != 3 {
+		t.Errorf("got max open connections %v, want 3", got)
+	}
+	conn, err := gormDB.DB().Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := metricValue(t, registry, "sdk_db_pool_in_use_connections", map[string]string{"db": "main"}); got != 1 {
+		t.Errorf("got %v connections in use, want 1", got)
+	}
+	_ = conn.Close()
+	if got := metricValue(t, registry, "sdk_db_pool_in_use_connections", map[string]string{"db": "main"}); got != 0 {
+		t.Errorf("got %v connections in use, want 0", got)
+	}
+}
+
+func TestObserveMongo(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m, err := New(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.observeMongo("find", "users", time.Millisecond, nil)
+	m.observeMongo("find", "users", time.Millisecond, mongo.ErrNoDocuments)
+	m.observeMongo("find", "users", time.Millisecond, errors.New("timeout"))
+
+	labels := map[string]string{"operation": "find", "collection": "users"}
+	if got := metricValue(t, registry, "sdk_mongodb_operations_total", labels); got != 3 {
+		t.Errorf("got %v operations, want 3", got)
+	}
+	if got := metricValue(t, registry, "sdk_mongodb_operation_errors_total", labels); got != 1 {
+		t.Errorf("got %v errors, want 1 without ErrNoDocuments", got)
+	}
+}
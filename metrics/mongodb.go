@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/uhhc/sdk-common-go/mongodb"
+)
+
+// InstrumentMongoClient to collect the operations of the client, the copies got by WithContext afterwards
+// are collected too
+// mongo.ErrNoDocuments is not counted as an error.
+func (m *Metrics) InstrumentMongoClient(mc *mongodb.MongoClient) *mongodb.MongoClient {
+	return mc.SetObserver(m.observeMongo)
+}
+
+func (m *Metrics) observeMongo(op, collection string, duration time.Duration, err error) {
+	m.mongoOps.WithLabelValues(op, collection).Inc()
+	m.mongoDuration.WithLabelValues(op, collection).Observe(duration.Seconds())
+	if err != nil && err != mongo.ErrNoDocuments {
+		m.mongoErrors.WithLabelValues(op, collection).Inc()
+	}
+}
@@ -11,31 +11,95 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 
+	sdkconfig "github.com/uhhc/sdk-common-go/config"
 	"github.com/uhhc/sdk-common-go/log"
+	"github.com/uhhc/sdk-common-go/types/pagination"
+	"github.com/uhhc/sdk-common-go/util/backoff"
 )
 
 // MongoClient represents the struct of mongodb client
 type MongoClient struct {
+	// ctx is the parent context of the operations
+	ctx         context.Context
 	dbname      string
+	opTimeout   int64
+	observer    OpObserver
 	client      *mongo.Client
 	logger      log.Logger
 	loggerClone log.Logger
 }
 
+// Config is the config for mongodb connection
+// The config tags are the keys of the config package, such as "mongodb.host" for the section "mongodb".
+type Config struct {
+	User     string `config:"user"`
+	Password string `config:"password"`
+	Host     string `config:"host" required:"true"`
+	Port     string `config:"port" default:"27017"`
+	SSL      string `config:"ssl" default:"false"`
+	DBName   string `config:"dbname"`
+	// UserFile and PasswordFile are read if User and Password are empty, such as the mounted secrets of Kubernetes
+	// User and Password can also be references like secret://<provider>/<name> of the config package.
+	UserFile     string `config:"user_file"`
+	PasswordFile string `config:"password_file"`
+	// ConnTimeout is the timeout of every connect attempt in seconds
+	ConnTimeout int64 `config:"conn_timeout" default:"10"`
+	// OpTimeout is the timeout of every operation in seconds, MONGODB_OP_TIMEOUT is used if it is 0
+	OpTimeout int64 `config:"op_timeout" default:"10"`
+
+	// Startup retry settings, the intervals are in seconds
+	ConnectRetries          int   `config:"connect_retries"`
+	ConnectRetryInterval    int64 `config:"connect_retry_interval"`
+	ConnectRetryMaxInterval int64 `config:"connect_retry_max_interval"`
+	ConnectMaxElapsedTime   int64 `config:"connect_max_elapsed_time"`
+}
+
 // NewMongoClient to get mongodb instance
 func NewMongoClient(logger log.Logger) (*MongoClient, error) {
+	return NewMongoClientWithConfig(logger, nil)
+}
+
+// NewMongoClientWithConfig to get mongodb instance with the given config
+// If config is nil, the config will be read from viper.
+func NewMongoClientWithConfig(logger log.Logger, config *Config) (*MongoClient, error) {
 	loggerClone := logger
 	logger.SugaredLogger = logger.With("method", "NewMongoClient")
 
-	viper.AutomaticEnv()
+	sdkconfig.GlobalEnv()
 
 	// Get config
-	user := viper.GetString("MONGODB_USER")
-	password := viper.GetString("MONGODB_PASSWORD")
-	host := viper.GetString("MONGODB_HOST")
-	port := viper.GetString("MONGODB_PORT")
-	ssl := viper.GetString("MONGODB_SSL")
-	timeout := viper.GetInt64("MONGODB_CONN_TIMEOUT")
+	if config == nil {
+		config = &Config{
+			User:                    viper.GetString("MONGODB_USER"),
+			Password:                viper.GetString("MONGODB_PASSWORD"),
+			UserFile:                viper.GetString("MONGODB_USER_FILE"),
+			PasswordFile:            viper.GetString("MONGODB_PASSWORD_FILE"),
+			Host:                    viper.GetString("MONGODB_HOST"),
+			Port:                    viper.GetString("MONGODB_PORT"),
+			SSL:                     viper.GetString("MONGODB_SSL"),
+			DBName:                  viper.GetString("MONGODB_DBNAME"),
+			ConnTimeout:             viper.GetInt64("MONGODB_CONN_TIMEOUT"),
+			ConnectRetries:          viper.GetInt("MONGODB_CONNECT_RETRIES"),
+			ConnectRetryInterval:    viper.GetInt64("MONGODB_CONNECT_RETRY_INTERVAL"),
+			ConnectRetryMaxInterval: viper.GetInt64("MONGODB_CONNECT_RETRY_MAX_INTERVAL"),
+			ConnectMaxElapsedTime:   viper.GetInt64("MONGODB_CONNECT_MAX_ELAPSED_TIME"),
+		}
+	}
+	// Resolve the credentials from the files or secret providers
+	user, err := sdkconfig.ResolveSecret(config.User, config.UserFile)
+	if err != nil {
+		logger.Errorw("resolve mongodb credentials error", "error", err)
+		return nil, err
+	}
+	password, err := sdkconfig.ResolveSecret(config.Password, config.PasswordFile)
+	if err != nil {
+		logger.Errorw("resolve mongodb credentials error", "error", err)
+		return nil, err
+	}
+	host := config.Host
+	port := config.Port
+	ssl := config.SSL
+	timeout := config.ConnTimeout
 
 	// Set default value
 	if ssl == "" {
@@ -45,38 +109,109 @@ func NewMongoClient(logger log.Logger) (*MongoClient, error) {
 		timeout = 10
 	}
 
-	// Set URI
-	// mongodb://[username:password@]host1[:port1][,...hostN[:portN]][/[database][?options]]
+	clientOpts := newClientOptions(host, port, ssl, user, password)
+	// Do not log the password
+	logger.Debugw("", "user", user, "host", host, "port", port, "ssl", ssl)
+
+	// Connect to MongoDB, retry with backoff if it is not ready yet
+	retry := &backoff.Policy{
+		Retries:         config.ConnectRetries,
+		InitialInterval: time.Duration(config.ConnectRetryInterval) * time.Second,
+		MaxInterval:     time.Duration(config.ConnectRetryMaxInterval) * time.Second,
+		MaxElapsedTime:  time.Duration(config.ConnectMaxElapsedTime) * time.Second,
+		Jitter:          backoff.DefaultJitter,
+	}
+	var client *mongo.Client
+	err = retry.Retry(context.Background(), func() error {
+		var connErr error
+		client, connErr = connect(clientOpts, time.Duration(timeout)*time.Second)
+		return connErr
+	}, func(attempt int, err error, wait time.Duration) {
+		logger.Warnw("connect to mongodb error, will retry", "error", err, "attempt", attempt, "wait", wait.String())
+	})
+	if err != nil {
+		logger.Errorw("connect to mongodb error", "error", err, "user", user, "host", host, "port", port)
+		return nil, err
+	}
+
+	return &MongoClient{
+		dbname:      config.DBName,
+		opTimeout:   config.OpTimeout,
+		client:      client,
+		logger:      logger,
+		loggerClone: loggerClone,
+	}, nil
+}
+
+// newClientOptions to get the options of the connection
+// The credentials are set by options.Credential instead of the URI, so that they need not be escaped
+// and are not shown in the errors of the URI.
+func newClientOptions(host, port, ssl, user, password string) *options.ClientOptions {
+	// mongodb://host1[:port1][,...hostN[:portN]][/[database][?options]]
 	// See https://docs.mongodb.com/manual/reference/connection-string/
-	var uri string
+	uri := fmt.Sprintf("mongodb://%s:%s/?ssl=%s", host, port, ssl)
+	opts := options.Client().ApplyURI(uri)
 	if user != "" && password != "" {
-		uri = fmt.Sprintf("mongodb://%s:%s@%s:%s/?ssl=%s", user, password, host, port, ssl)
-	} else {
-		uri = fmt.Sprintf("mongodb://%s:%s/?ssl=%s", host, port, ssl)
+		opts.SetAuth(options.Credential{Username: user, Password: password})
 	}
-	// Do not log the password
-	logger.Debugw("", "user", user, "host", host, "port", port, "ssl", ssl)
+	return opts
+}
 
-	// Connect to MongoDB
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+func connect(opts *options.ClientOptions, timeout time.Duration) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
-		logger.Errorw("connect to mongodb error", "error", err, "uri", uri)
 		return nil, err
 	}
 
 	// Check the connection
 	if err = client.Ping(ctx, readpref.Primary()); err != nil {
-		logger.Errorw("ping mongodb error", "error", err, "uri", uri)
+		_ = client.Disconnect(context.Background())
 		return nil, err
 	}
+	return client, nil
+}
 
-	return &MongoClient{
-		client:      client,
-		logger:      logger,
-		loggerClone: loggerClone,
-	}, nil
+// WithContext to get a copy of the client whose operations are bound to ctx
+// The operations are canceled with ctx, and log with the logger and request-scoped fields of ctx.
+// Example:
+//
+// 		res, err := mongo.WithContext(ctx).InsertOne(collectionName, data)
+//
+func (mc *MongoClient) WithContext(ctx context.Context) *MongoClient {
+	c := *mc
+	c.ctx = ctx
+	c.loggerClone = *log.ContextLogger(ctx, &mc.loggerClone)
+	c.logger = c.loggerClone
+	return &c
+}
+
+// Ping to check the connection to the primary, such as for the health checks
+// The timeout is the one of ctx, or the operation timeout if ctx has no deadline.
+func (mc *MongoClient) Ping(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, mc.opTimeoutDuration())
+		defer cancel()
+	}
+	return mc.client.Ping(ctx, readpref.Primary())
+}
+
+// OpObserver is called after every operation of the client, such as to collect metrics
+// op is the operation like "insert_one", err is the error of the operation, which may be mongo.ErrNoDocuments.
+type OpObserver func(op, collection string, duration time.Duration, err error)
+
+// SetObserver to observe the operations of the client, the copies got by WithContext afterwards share the observer
+func (mc *MongoClient) SetObserver(observer OpObserver) *MongoClient {
+	mc.observer = observer
+	return mc
+}
+
+func (mc *MongoClient) observe(op, collection string, start time.Time, err error) {
+	if mc.observer != nil {
+		mc.observer(op, collection, time.Since(start), err)
+	}
 }
 
 // SetDatabase to set default database
@@ -96,12 +231,26 @@ func (mc *MongoClient) getDbHandler() *mongo.Database {
 }
 
 func (mc *MongoClient) getContext() context.Context {
-	timeout := viper.GetInt64("MONGODB_OP_TIMEOUT")
+	parent := mc.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	timeout := mc.opTimeoutDuration()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	// The operations do not cancel the context, it is released when the timeout expires
+	time.AfterFunc(timeout, cancel)
+	return ctx
+}
+
+func (mc *MongoClient) opTimeoutDuration() time.Duration {
+	timeout := mc.opTimeout
+	if timeout == 0 {
+		timeout = viper.GetInt64("MONGODB_OP_TIMEOUT")
+	}
 	if timeout == 0 {
 		timeout = 10
 	}
-	ctx, _ := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	return ctx
+	return time.Duration(timeout) * time.Second
 }
 
 // GetCollectionHandler to get a collection handler
@@ -140,8 +289,11 @@ func (mc *MongoClient) InsertOne(collectionName string, data interface{}, opts .
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "InsertOne")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	res, err := collection.InsertOne(mc.getContext(), data, opts...)
+	start := time.Now()
+	res, err := collection.InsertOne(ctx, data, opts...)
+	mc.observe("insert_one", collectionName, start, err)
 	if err != nil {
 		mc.logger.Errorw("insert one data error", "error", err)
 		return res, err
@@ -181,8 +333,11 @@ func (mc *MongoClient) InsertMany(collectionName string, data []interface{}, opt
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "InsertMany")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	res, err := collection.InsertMany(mc.getContext(), data, opts...)
+	start := time.Now()
+	res, err := collection.InsertMany(ctx, data, opts...)
+	mc.observe("insert_many", collectionName, start, err)
 	if err != nil {
 		mc.logger.Errorw("insert many data error", "error", err)
 		return res, err
@@ -206,8 +361,11 @@ func (mc *MongoClient) GetOne(collectionName string, filter interface{}, result
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "GetOne")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	err := collection.FindOne(mc.getContext(), filter, opts...).Decode(result)
+	start := time.Now()
+	err := collection.FindOne(ctx, filter, opts...).Decode(result)
+	mc.observe("get_one", collectionName, start, err)
 	if err != nil {
 		mc.logger.Errorw("get one data error", "error", err)
 		return err
@@ -243,13 +401,17 @@ func (mc *MongoClient) GetManyWithBsonFmt(collectionName string, filter interfac
 
 	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
+	start := time.Now()
 	cur, err := collection.Find(ctx, filter, opts...)
 	if err != nil {
+		mc.observe("get_many", collectionName, start, err)
 		mc.logger.Errorw("find collection data error", "error", err)
 		return nil, err
 	}
 	var results []bson.M
-	if err := cur.All(ctx, &results); err != nil {
+	err = cur.All(ctx, &results)
+	mc.observe("get_many", collectionName, start, err)
+	if err != nil {
 		mc.logger.Errorw("get many data error", "error", err)
 		return nil, err
 	}
@@ -257,6 +419,59 @@ func (mc *MongoClient) GetManyWithBsonFmt(collectionName string, filter interfac
 	return &results, nil
 }
 
+// GetPage to get one page of documents and the total amount
+// page starts from 1, results must be a pointer to a slice
+// Example:
+//
+// 		var results []test
+// 		opts := options.Find().SetSort(bson.D{{"test_id", 1}})
+// 		page, err := mongo.GetPage(collectionName, bson.D{{"name", "name001"}}, 1, 20, &results, opts)
+// 		fmt.Printf("total: %d, results: %+v\n", page.Total, results)
+//
+func (mc *MongoClient) GetPage(collectionName string, filter interface{}, page, size int, results interface{}, opts ...*options.FindOptions) (*pagination.Page, error) {
+	mc.logger = mc.loggerClone
+	mc.logger.SugaredLogger = mc.logger.With("method", "GetPage")
+
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = 20
+	}
+
+	ctx := mc.getContext()
+	collection := mc.GetCollectionHandler(collectionName)
+	start := time.Now()
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		mc.observe("get_page", collectionName, start, err)
+		mc.logger.Errorw("count documents error", "error", err)
+		return nil, err
+	}
+
+	opts = append(opts, options.Find().SetSkip(int64((page-1)*size)).SetLimit(int64(size)))
+	cur, err := collection.Find(ctx, filter, opts...)
+	if err != nil {
+		mc.observe("get_page", collectionName, start, err)
+		mc.logger.Errorw("find collection data error", "error", err)
+		return nil, err
+	}
+	err = cur.All(ctx, results)
+	mc.observe("get_page", collectionName, start, err)
+	if err != nil {
+		mc.logger.Errorw("get page data error", "error", err)
+		return nil, err
+	}
+
+	return &pagination.Page{
+		Items:   results,
+		Page:    page,
+		Size:    size,
+		Total:   total,
+		HasMore: int64(page*size) < total,
+	}, nil
+}
+
 // UpdateOne to update one document
 // See https://godoc.org/go.mongodb.org/mongo-driver/mongo#Collection.UpdateOne
 // Example:
@@ -274,8 +489,11 @@ func (mc *MongoClient) UpdateOne(collectionName string, filter interface{}, upda
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "UpdateOne")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	res, err := collection.UpdateOne(mc.getContext(), filter, update, opts...)
+	start := time.Now()
+	res, err := collection.UpdateOne(ctx, filter, update, opts...)
+	mc.observe("update_one", collectionName, start, err)
 	if err != nil {
 		mc.logger.Errorw("update one data error", "error", err)
 		return res, err
@@ -300,8 +518,11 @@ func (mc *MongoClient) UpdateMany(collectionName string, filter interface{}, upd
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "UpdateMany")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	res, err := collection.UpdateMany(mc.getContext(), filter, update, opts...)
+	start := time.Now()
+	res, err := collection.UpdateMany(ctx, filter, update, opts...)
+	mc.observe("update_many", collectionName, start, err)
 	if err != nil {
 		mc.logger.Errorw("update many data error", "error", err)
 		return res, err
@@ -325,8 +546,11 @@ func (mc *MongoClient) DeleteOne(collectionName string, filter interface{}, opts
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "DeleteOne")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	res, err := collection.DeleteOne(mc.getContext(), filter, opts...)
+	start := time.Now()
+	res, err := collection.DeleteOne(ctx, filter, opts...)
+	mc.observe("delete_one", collectionName, start, err)
 	if err != nil {
 		mc.logger.Errorw("delete one data error", "error", err)
 		return res, err
@@ -350,8 +574,11 @@ func (mc *MongoClient) DeleteMany(collectionName string, filter interface{}, opt
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "DeleteMany")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	res, err := collection.DeleteMany(mc.getContext(), filter, opts...)
+	start := time.Now()
+	res, err := collection.DeleteMany(ctx, filter, opts...)
+	mc.observe("delete_many", collectionName, start, err)
 	if err != nil {
 		mc.logger.Errorw("delete many data error", "error", err)
 		return res, err
@@ -374,8 +601,11 @@ func (mc *MongoClient) Distinct(collectionName string, fieldName string, filter
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "Distinct")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	res, err := collection.Distinct(mc.getContext(), fieldName, filter, opts...)
+	start := time.Now()
+	res, err := collection.Distinct(ctx, fieldName, filter, opts...)
+	mc.observe("distinct", collectionName, start, err)
 	if err != nil {
 		mc.logger.Errorw("get distinct data error", "error", err)
 		return res, err
@@ -398,8 +628,11 @@ func (mc *MongoClient) CountDocumentsByFilter(collectionName string, filter inte
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "CountDocuments")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	total, err := collection.CountDocuments(mc.getContext(), filter, opts...)
+	start := time.Now()
+	total, err := collection.CountDocuments(ctx, filter, opts...)
+	mc.observe("count_documents", collectionName, start, err)
 	mc.logger.Infow("", "opts", opts)
 	if err != nil {
 		mc.logger.Errorw("count documents by filter error", "error", err)
@@ -423,8 +656,11 @@ func (mc *MongoClient) CountDocumentsTotal(collectionName string, opts ...*optio
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "CountDocuments")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	total, err := collection.EstimatedDocumentCount(mc.getContext(), opts...)
+	start := time.Now()
+	total, err := collection.EstimatedDocumentCount(ctx, opts...)
+	mc.observe("count_documents_total", collectionName, start, err)
 	if err != nil {
 		mc.logger.Errorw("count documents total error", "error", err)
 		return total, err
@@ -447,8 +683,11 @@ func (mc *MongoClient) CreateOneIndex(collectionName string, model mongo.IndexMo
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "CreateOneIndex")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	res, err := collection.Indexes().CreateOne(mc.getContext(), model, opts...)
+	start := time.Now()
+	res, err := collection.Indexes().CreateOne(ctx, model, opts...)
+	mc.observe("create_one_index", collectionName, start, err)
 	if err != nil {
 		mc.logger.Errorw(
 			"create one index error",
@@ -482,8 +721,11 @@ func (mc *MongoClient) CreateManyIndexes(collectionName string, models []mongo.I
 	mc.logger = mc.loggerClone
 	mc.logger.SugaredLogger = mc.logger.With("method", "CreateManyIndexes")
 
+	ctx := mc.getContext()
 	collection := mc.GetCollectionHandler(collectionName)
-	res, err := collection.Indexes().CreateMany(mc.getContext(), models, opts...)
+	start := time.Now()
+	res, err := collection.Indexes().CreateMany(ctx, models, opts...)
+	mc.observe("create_many_indexes", collectionName, start, err)
 	if err != nil {
 		mc.logger.Errorw(
 			"create many indexes error",
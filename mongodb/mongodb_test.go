@@ -0,0 +1,33 @@
+package mongodb
+
+import (
+	"testing"
+)
+
+func TestClientOptionsCredentials(t *testing.T) {
+	// The characters which are reserved in the connection string
+	password := "p@ss:w/rd?#%+"
+	opts := newClientOptions("db.example.com", "27017", "true", "user@corp", password)
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Auth == nil || opts.Auth.Username != "user@corp" || opts.Auth.Password != password {
+		t.Errorf("got credential %+v, want the user and password unchanged", opts.Auth)
+	}
+	if len(opts.Hosts) != 1 || opts.Hosts[0] != "db.example.com:27017" {
+		t.Errorf("got hosts %v, want db.example.com:27017", opts.Hosts)
+	}
+	if opts.TLSConfig == nil {
+		t.Error("got no TLS config, want ssl enabled")
+	}
+}
+
+func TestClientOptionsWithoutCredentials(t *testing.T) {
+	opts := newClientOptions("localhost", "27017", "false", "", "")
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Auth != nil {
+		t.Errorf("got credential %+v, want none", opts.Auth)
+	}
+}
@@ -0,0 +1,87 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalidCursor is returned when the cursor can not be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page represents one page of a list result
+// Page is set in offset mode, NextCursor is set in keyset mode.
+type Page struct {
+	Items      interface{} `json:"items"`
+	Page       int         `json:"page,omitempty"`
+	Size       int         `json:"size"`
+	Total      int64       `json:"total"`
+	HasMore    bool        `json:"hasMore"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
+
+// TotalPages to get the amount of pages
+func (p *Page) TotalPages() int64 {
+	if p.Size <= 0 {
+		return 0
+	}
+	return (p.Total + int64(p.Size) - 1) / int64(p.Size)
+}
+
+// cursorTimeKey marks a time value in the cursor, so that it is decoded as time.Time instead of string
+const cursorTimeKey = "$time"
+
+// EncodeCursor to encode the sort values of the last item into an opaque cursor
+// The type of time.Time values is kept in the cursor, so that they are decoded as time.Time.
+func EncodeCursor(values []interface{}) (string, error) {
+	encoded := make([]interface{}, len(values))
+	for i, v := range values {
+		if t, ok := v.(time.Time); ok {
+			v = map[string]string{cursorTimeKey: t.Format(time.RFC3339Nano)}
+		}
+		encoded[i] = v
+	}
+	b, err := json.Marshal(encoded)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor to decode the cursor into the sort values
+// Integers are decoded as int64, other numbers as float64 and time as time.Time.
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var values []interface{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	for i, v := range values {
+		switch v := v.(type) {
+		case json.Number:
+			if iv, err := v.Int64(); err == nil {
+				values[i] = iv
+			} else if fv, err := v.Float64(); err == nil {
+				values[i] = fv
+			}
+		case map[string]interface{}:
+			s, ok := v[cursorTimeKey].(string)
+			if !ok || len(v) != 1 {
+				return nil, ErrInvalidCursor
+			}
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			values[i] = t
+		}
+	}
+	return values, nil
+}
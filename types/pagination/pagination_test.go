@@ -0,0 +1,40 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2020, 5, 1, 8, 30, 0, 123456789, time.FixedZone("CST", 8*3600))
+	cursor, err := EncodeCursor([]interface{}{createdAt, int64(42), 1.5, "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := DecodeCursor(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 4 {
+		t.Fatalf("got %d values, want 4", len(values))
+	}
+	if got, ok := values[0].(time.Time); !ok || !got.Equal(createdAt) {
+		t.Errorf("got %#v, want time %s", values[0], createdAt)
+	}
+	if values[1] != int64(42) || values[2] != 1.5 || values[3] != "name" {
+		t.Errorf("got %#v, want 42, 1.5 and name", values[1:])
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	for _, cursor := range []string{
+		"not base64!",
+		"e30",                        // {}
+		"W3siJHRpbWUiOiJub3cifV0",    // [{"$time":"now"}]
+		"W3siJHRpbWUiOjEsImEiOjJ9XQ", // [{"$time":1,"a":2}]
+	} {
+		if _, err := DecodeCursor(cursor); err != ErrInvalidCursor {
+			t.Errorf("cursor %q: got error %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
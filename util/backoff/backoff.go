@@ -0,0 +1,110 @@
+package backoff
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Default values of the exponential backoff policy
+const (
+	DefaultInitialInterval = 1 * time.Second
+	DefaultMaxInterval     = 30 * time.Second
+	DefaultMultiplier      = 2.0
+	DefaultJitter          = 0.5
+)
+
+// Policy represents an exponential backoff policy with jitter
+type Policy struct {
+	// Retries is the max number of retries after the first attempt, 0 means no retry
+	Retries int
+	// InitialInterval is the wait time before the first retry
+	InitialInterval time.Duration
+	// MaxInterval caps the wait time between two attempts
+	MaxInterval time.Duration
+	// MaxElapsedTime stops retrying once it is exceeded, 0 means no limit
+	MaxElapsedTime time.Duration
+	// Multiplier is the factor the interval grows by after every attempt
+	Multiplier float64
+	// Jitter is the randomization factor in [0, 1] applied to every interval
+	Jitter float64
+}
+
+// NotifyFunc is called before sleeping for the next attempt
+type NotifyFunc func(attempt int, err error, wait time.Duration)
+
+// Interval to get the wait time before the given retry attempt (starting from 1)
+// The policy is not changed, so it can be shared by the goroutines.
+func (p *Policy) Interval(attempt int) time.Duration {
+	c := p.withDefaults()
+	interval := float64(c.InitialInterval) * math.Pow(c.Multiplier, float64(attempt-1))
+	if interval > float64(c.MaxInterval) {
+		interval = float64(c.MaxInterval)
+	}
+	if c.Jitter > 0 {
+		delta := c.Jitter * interval
+		interval = interval - delta + rand.Float64()*(2*delta)
+	}
+	return time.Duration(interval)
+}
+
+// Retry to run op until it succeeds or the policy is exhausted
+// The last error of op is returned when all attempts fail.
+// Example:
+//
+// 		policy := &backoff.Policy{Retries: 5, MaxElapsedTime: time.Minute}
+// 		err := policy.Retry(context.Background(), func() error {
+// 			return connect()
+// 		}, func(attempt int, err error, wait time.Duration) {
+// 			logger.Warnw("connect error", "attempt", attempt, "error", err, "wait", wait)
+// 		})
+//
+func (p *Policy) Retry(ctx context.Context, op func() error, notify NotifyFunc) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		if attempt > p.Retries {
+			return err
+		}
+		wait := p.Interval(attempt)
+		if p.MaxElapsedTime > 0 && time.Since(start)+wait > p.MaxElapsedTime {
+			return err
+		}
+		if notify != nil {
+			notify(attempt, err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// withDefaults to get a copy of the policy with the default values of the unset fields
+func (p *Policy) withDefaults() Policy {
+	c := *p
+	if c.InitialInterval <= 0 {
+		c.InitialInterval = DefaultInitialInterval
+	}
+	if c.MaxInterval <= 0 {
+		c.MaxInterval = DefaultMaxInterval
+	}
+	if c.Multiplier < 1 {
+		c.Multiplier = DefaultMultiplier
+	}
+	if c.Jitter < 0 || c.Jitter > 1 {
+		c.Jitter = DefaultJitter
+	}
+	return c
+}
@@ -0,0 +1,123 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		attempt int
+		want    time.Duration
+	}{
+		{"first", Policy{InitialInterval: 100 * time.Millisecond}, 1, 100 * time.Millisecond},
+		{"doubled", Policy{InitialInterval: 100 * time.Millisecond}, 3, 400 * time.Millisecond},
+		{"multiplier", Policy{InitialInterval: 100 * time.Millisecond, Multiplier: 3}, 3, 900 * time.Millisecond},
+		{"capped", Policy{InitialInterval: time.Second, MaxInterval: 5 * time.Second}, 10, 5 * time.Second},
+		{"defaults", Policy{}, 1, DefaultInitialInterval},
+		{"default max", Policy{}, 20, DefaultMaxInterval},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Interval(tt.attempt); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIntervalJitter(t *testing.T) {
+	p := &Policy{InitialInterval: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if got := p.Interval(1); got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("got %s, want it in [500ms, 1.5s]", got)
+		}
+	}
+	// An invalid jitter falls back to the default
+	p = &Policy{InitialInterval: time.Second, Jitter: 2}
+	for i := 0; i < 100; i++ {
+		if got := p.Interval(1); got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("got %s, want the default jitter", got)
+		}
+	}
+}
+
+func TestIntervalKeepsPolicy(t *testing.T) {
+	p := &Policy{Jitter: -1}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Interval(1)
+		}()
+	}
+	wg.Wait()
+	if *p != (Policy{Jitter: -1}) {
+		t.Errorf("got policy %+v, want it unchanged", *p)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name         string
+		policy       Policy
+		failures     int
+		wantErr      error
+		wantAttempts int
+	}{
+		{"succeeds at once", Policy{Retries: 3}, 0, nil, 1},
+		{"succeeds after retries", Policy{Retries: 3}, 2, nil, 3},
+		{"no retry", Policy{}, 5, errFailed, 1},
+		{"retries exhausted", Policy{Retries: 2}, 5, errFailed, 3},
+		{"max elapsed time", Policy{Retries: 10, InitialInterval: 20 * time.Millisecond, MaxElapsedTime: 50 * time.Millisecond}, 20, errFailed, 2},
+	}
+	for _, tt := range tests {
+		if tt.policy.InitialInterval == 0 {
+			tt.policy.InitialInterval = time.Millisecond
+		}
+		attempts := 0
+		var notified []int
+		err := tt.policy.Retry(context.Background(), func() error {
+			attempts++
+			if attempts <= tt.failures {
+				return errFailed
+			}
+			return nil
+		}, func(attempt int, err error, wait time.Duration) {
+			if err != errFailed || wait <= 0 {
+				t.Errorf("%s: got notified error %v and wait %s", tt.name, err, wait)
+			}
+			notified = append(notified, attempt)
+		})
+		if err != tt.wantErr || attempts != tt.wantAttempts {
+			t.Errorf("%s: got error %v after %d attempts, want %v after %d", tt.name, err, attempts, tt.wantErr, tt.wantAttempts)
+		}
+		for i, attempt := range notified {
+			if attempt != i+1 {
+				t.Errorf("%s: got notified attempts %v", tt.name, notified)
+				break
+			}
+		}
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	errFailed := errors.New("failed")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	p := &Policy{Retries: 10, InitialInterval: time.Hour}
+	attempts := 0
+	start := time.Now()
+	err := p.Retry(ctx, func() error {
+		attempts++
+		return errFailed
+	}, nil)
+	if err != errFailed || attempts != 1 || time.Since(start) > time.Second {
+		t.Errorf("got error %v after %d attempts in %s, want the last error once ctx is done", err, attempts, time.Since(start))
+	}
+}